@@ -0,0 +1,229 @@
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// accountBundleVersion - bump on any incompatible change to AccountBundle
+// (2: password hashes are hex encoded)
+const accountBundleVersion = 2
+
+// hashColumns - mysql.user columns holding password hashes, hex encoded in the JSON since
+// ie: caching_sha2_password hashes are binary and JSON would replace them with U+FFFD
+var hashColumns = []string{"authentication_string", "Password"}
+
+// AccountBundle - users, grants and binlog retention of an instance as exported by
+// ExportAccounts, rows are kept as dumpQuery returns them (by composite primary key)
+// so importing goes through exactly the same code as a live clone
+type AccountBundle struct {
+	Version              int                           `json:"version"`
+	Source               string                        `json:"source"`
+	MySQLVersion         string                        `json:"mysql_version"`
+	ExportedAt           time.Time                     `json:"exported_at"`
+	BinlogRetentionHours *int                          `json:"binlog_retention_hours,omitempty"` // overrides RDSConfiguration
+	RDSConfiguration     map[string]*string            `json:"rds_configuration,omitempty"`      // mysql.rds_configuration
+	Users                map[string]map[string]*string `json:"users"`                            // mysql.user
+	Grants               map[string]map[string]*string `json:"grants"`                           // mysql.db
+	TablesPriv           map[string]map[string]*string `json:"tables_priv"`                      // mysql.tables_priv
+	ColumnsPriv          map[string]map[string]*string `json:"columns_priv"`                     // mysql.columns_priv
+	ProcsPriv            map[string]map[string]*string `json:"procs_priv"`                       // mysql.procs_priv
+}
+
+func (b *AccountBundle) objectGrants() *objectGrants {
+	return &objectGrants{tables: b.TablesPriv, columns: b.ColumnsPriv, procs: b.ProcsPriv}
+}
+
+// rdsConfiguration - RDSConfiguration with BinlogRetentionHours applied
+func (b *AccountBundle) rdsConfiguration() map[string]*string {
+	conf := make(map[string]*string)
+	for name, value := range b.RDSConfiguration {
+		conf[name] = value
+	}
+	if b.BinlogRetentionHours != nil {
+		hrs := strconv.Itoa(*b.BinlogRetentionHours)
+		conf[binlogRetentionHours] = &hrs
+	}
+	return conf
+}
+
+// WriteJSON - machine readable bundle, see ReadAccountBundle
+func (b *AccountBundle) WriteJSON(w io.Writer) error {
+	encoded := *b
+	encoded.Users, _ = mapHashes(b.Users, func(hash string) (string, error) {
+		return hex.EncodeToString([]byte(hash)), nil
+	})
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(&encoded)
+}
+
+// mapHashes - copy of `users` with `f` applied to every hashColumns value
+func mapHashes(users map[string]map[string]*string, f func(string) (string, error)) (map[string]map[string]*string, error) {
+	mapped := make(map[string]map[string]*string)
+	for user, privs := range users {
+		row := make(map[string]*string)
+		for col, v := range privs {
+			row[col] = v
+		}
+		for _, col := range hashColumns {
+			if row[col] == nil {
+				continue
+			}
+			hash, err := f(*row[col])
+			if err != nil {
+				return nil, fmt.Errorf("%s of %q: %v", col, user, err)
+			}
+			row[col] = &hash
+		}
+		mapped[user] = row
+	}
+	return mapped, nil
+}
+
+// ReadAccountBundle - read back what WriteJSON wrote
+func ReadAccountBundle(r io.Reader) (*AccountBundle, error) {
+	b := &AccountBundle{}
+	if err := json.NewDecoder(r).Decode(b); err != nil {
+		return nil, fmt.Errorf("ERROR: can't parse account bundle: %v", err)
+	}
+	if b.Version != accountBundleVersion {
+		return nil, fmt.Errorf("ERROR: account bundle version %d is not supported, want %d", b.Version, accountBundleVersion)
+	}
+
+	users, err := mapHashes(b.Users, func(hash string) (string, error) {
+		decoded, err := hex.DecodeString(hash)
+		return string(decoded), err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ERROR: can't decode account bundle: %v", err)
+	}
+	b.Users = users
+	return b, nil
+}
+
+// WriteSQL - the bundle as a script for the mysql client, meant for an instance that
+// has none of these users yet (system users are left out), see ImportAccounts otherwise
+func (b *AccountBundle) WriteSQL(w io.Writer) error {
+	v, err := parseMySQLVersion(b.MySQLVersion)
+	if err != nil {
+		return err
+	}
+
+	lines := []string{
+		fmt.Sprintf("-- account bundle v%d: %q (MySQL %s) exported at %s", b.Version, b.Source, b.MySQLVersion, b.ExportedAt.Format(time.RFC3339)),
+	}
+
+	users := []string{}
+	for user := range b.Users {
+		users = append(users, user)
+	}
+	sort.Strings(users)
+
+	objectGrants := b.objectGrants()
+	for _, user := range users {
+		privs := b.Users[user]
+		if systemUsers[*privs["User"]] {
+			continue
+		}
+
+		lines = append(lines, "", fmt.Sprintf("-- %s", user))
+		cmds := createUserCMDsFor(privs, v)
+		for _, row := range sortedRows(b.Grants) {
+			if !isAccount(row, *privs["Host"], *privs["User"]) {
+				continue
+			}
+			if cmd := giveGrantsCMD(row); cmd != "" {
+				cmds = append(cmds, cmd)
+			}
+		}
+		cmds = append(cmds, objectGrants.grantCMDs(*privs["Host"], *privs["User"])...)
+
+		for _, cmd := range cmds {
+			lines = append(lines, cmd+";")
+		}
+	}
+
+	conf := b.rdsConfiguration()
+	names := []string{}
+	for name := range conf {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) > 0 {
+		lines = append(lines, "")
+	}
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("call mysql.rds_set_configuration('%s', %s);", quote(name), configValue(conf[name])))
+	}
+
+	_, err = io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+// ExportAccounts - users, grants and RDS configuration (binlog retention, ...) of `i` into `path` (JSON, the one
+// ImportAccounts reads) and `path`.sql (the same as a script), ie: to keep the accounts
+// of an old- instance around after it's deleted, both contain password hashes
+func (s *SDK) ExportAccounts(i Instance, rootPass, path string) (*AccountBundle, error) {
+	if i.Engine != "mysql" {
+		return nil, fmt.Errorf("can't export accounts of %q, engine %q is not supported", i.Name, i.Engine)
+	}
+
+	c := &mysqlReplicaClone{copyFrom: i, rootPass: rootPass, log: s.log}
+	if err := c.copyFrom.connect("root", rootPass, "mysql"); err != nil {
+		return nil, err
+	}
+	defer c.copyFrom.DB.Close()
+
+	b, err := c.dumpAccounts()
+	if err != nil {
+		return nil, err
+	}
+
+	var js, script strings.Builder
+	if err := b.WriteJSON(&js); err != nil {
+		return nil, err
+	}
+	if err := b.WriteSQL(&script); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(path, []byte(js.String()), 0600); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(path+".sql", []byte(script.String()), 0600); err != nil {
+		return nil, err
+	}
+
+	s.log.Printf("... ExportAccounts: [%24s] exported %d users to %q", i.Name, len(b.Users), path)
+	return b, nil
+}
+
+// ImportAccounts - replay the bundle ExportAccounts wrote to `path` into `i`, users
+// that already exist are left alone or reconciled as per `users`
+func (s *SDK) ImportAccounts(i Instance, rootPass, path string, users UserCloneOptions, dryRun bool) error {
+	if i.Engine != "mysql" {
+		return fmt.Errorf("can't import accounts into %q, engine %q is not supported", i.Name, i.Engine)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	b, err := ReadAccountBundle(f)
+	if err != nil {
+		return err
+	}
+
+	s.log.Printf("... ImportAccounts: [%24s] importing %d users of %q exported at %s", i.Name, len(b.Users), b.Source, b.ExportedAt.Format(time.RFC3339))
+	c := &mysqlReplicaClone{copyTo: i, rootPass: rootPass, users: users, log: s.log}
+	return c.apply(b, s.Verbose, dryRun)
+}
@@ -0,0 +1,81 @@
+import (
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// binaryHash - caching_sha2_password style hash, not valid UTF-8
+const binaryHash = "$A$005$\x01\x7f\xfe\xff'\\\x00salt0123456789abcdefghijklmnopqrstuvwxyzABCD"
+
+func testBundle() *AccountBundle {
+	return &AccountBundle{
+		Version:              accountBundleVersion,
+		Source:               "prod-one",
+		MySQLVersion:         "8.0.28",
+		ExportedAt:           time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		BinlogRetentionHours: aws.Int(72),
+		RDSConfiguration:     map[string]*string{"binlog retention hours": aws.String("24"), "target delay": aws.String("0")},
+		Users: map[string]map[string]*string{
+			"app|%": {
+				"Host":                  aws.String("%"),
+				"User":                  aws.String("app"),
+				"authentication_string": aws.String(binaryHash),
+				"plugin":                aws.String("caching_sha2_password"),
+				"Select_priv":           aws.String("Y"),
+				"Grant_priv":            aws.String("N"),
+			},
+		},
+		Grants: map[string]map[string]*string{},
+	}
+}
+
+func TestAccountBundleJSONRoundTrip(t *testing.T) {
+	b := testBundle()
+
+	var js strings.Builder
+	if err := b.WriteJSON(&js); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(js.String(), `\ufffd`) {
+		t.Fatalf("hash got mangled in the JSON:\n%s", js.String())
+	}
+
+	got, err := ReadAccountBundle(strings.NewReader(js.String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Users, b.Users) {
+		t.Errorf("users differ after the round trip: got %q, want %q",
+			*got.Users["app|%"]["authentication_string"], binaryHash)
+	}
+	if aws.IntValue(got.BinlogRetentionHours) != 72 || !reflect.DeepEqual(got.rdsConfiguration(), b.rdsConfiguration()) {
+		t.Errorf("RDS configuration differs after the round trip: got %v, want %v", got.rdsConfiguration(), b.rdsConfiguration())
+	}
+}
+
+func TestAccountBundleWriteSQL(t *testing.T) {
+	var script strings.Builder
+	if err := testBundle().WriteSQL(&script); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"CREATE USER 'app'@'%' IDENTIFIED WITH caching_sha2_password AS 0x" + hex.EncodeToString([]byte(binaryHash)) + ";",
+		"call mysql.rds_set_configuration('binlog retention hours', '72');",
+		"call mysql.rds_set_configuration('target delay', '0');",
+	} {
+		if !strings.Contains(script.String(), want) {
+			t.Errorf("script doesn't contain %q:\n%s", want, script.String())
+		}
+	}
+}
+
+func TestReadAccountBundleVersion(t *testing.T) {
+	if _, err := ReadAccountBundle(strings.NewReader(`{"version": 1}`)); err == nil {
+		t.Error("version 1 bundle (raw hashes) was accepted")
+	}
+}
@@ -0,0 +1,183 @@
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+// restorableAttr - an attribute RestoreDBInstanceFromDBSnapshot (and friends) don't take
+// but ModifyDBInstance does, `get` returns a comparable value and `set` copies it from
+// the source instance into the modify request, or returns false if it can't (it's then
+// left alone and reported as not matched)
+type restorableAttr struct {
+	name string
+	get  func(db *rds.DBInstance) string
+	set  func(src *rds.DBInstance, in *rds.ModifyDBInstanceInput) bool
+}
+
+var restorableAttrs = []restorableAttr{
+	{
+		"BackupRetentionPeriod",
+		func(db *rds.DBInstance) string { return fmt.Sprint(aws.Int64Value(db.BackupRetentionPeriod)) },
+		func(src *rds.DBInstance, in *rds.ModifyDBInstanceInput) bool {
+			in.BackupRetentionPeriod = src.BackupRetentionPeriod
+			return true
+		},
+	},
+	{
+		"PreferredBackupWindow",
+		func(db *rds.DBInstance) string { return aws.StringValue(db.PreferredBackupWindow) },
+		func(src *rds.DBInstance, in *rds.ModifyDBInstanceInput) bool {
+			in.PreferredBackupWindow = src.PreferredBackupWindow
+			return true
+		},
+	},
+	{
+		"PreferredMaintenanceWindow",
+		func(db *rds.DBInstance) string { return aws.StringValue(db.PreferredMaintenanceWindow) },
+		func(src *rds.DBInstance, in *rds.ModifyDBInstanceInput) bool {
+			in.PreferredMaintenanceWindow = src.PreferredMaintenanceWindow
+			return true
+		},
+	},
+	{
+		"DeletionProtection",
+		func(db *rds.DBInstance) string { return fmt.Sprint(aws.BoolValue(db.DeletionProtection)) },
+		func(src *rds.DBInstance, in *rds.ModifyDBInstanceInput) bool {
+			in.DeletionProtection = src.DeletionProtection
+			return true
+		},
+	},
+	{
+		"IAMDatabaseAuthenticationEnabled",
+		func(db *rds.DBInstance) string { return fmt.Sprint(aws.BoolValue(db.IAMDatabaseAuthenticationEnabled)) },
+		func(src *rds.DBInstance, in *rds.ModifyDBInstanceInput) bool {
+			in.EnableIAMDatabaseAuthentication = src.IAMDatabaseAuthenticationEnabled
+			return true
+		},
+	},
+	{
+		"CACertificateIdentifier",
+		func(db *rds.DBInstance) string { return aws.StringValue(db.CACertificateIdentifier) },
+		func(src *rds.DBInstance, in *rds.ModifyDBInstanceInput) bool {
+			in.CACertificateIdentifier = src.CACertificateIdentifier
+			return true
+		},
+	},
+	{
+		// interval > 0 requires the role, so they always go together, the role is
+		// nil when it can't be used where the instance is (see RestoreOverrides)
+		"MonitoringInterval",
+		func(db *rds.DBInstance) string {
+			return fmt.Sprintf("%d %s", aws.Int64Value(db.MonitoringInterval), aws.StringValue(db.MonitoringRoleArn))
+		},
+		func(src *rds.DBInstance, in *rds.ModifyDBInstanceInput) bool {
+			if aws.Int64Value(src.MonitoringInterval) > 0 {
+				if src.MonitoringRoleArn == nil {
+					return false
+				}
+				in.MonitoringRoleArn = src.MonitoringRoleArn
+			}
+			in.MonitoringInterval = aws.Int64(aws.Int64Value(src.MonitoringInterval))
+			return true
+		},
+	},
+	{
+		// the KMS key can't be changed once Performance Insights is enabled,
+		// if it ends up different it's reported as not matched, same as when
+		// it's nil since the source's can't be used (see RestoreOverrides)
+		"PerformanceInsightsEnabled",
+		func(db *rds.DBInstance) string {
+			if !aws.BoolValue(db.PerformanceInsightsEnabled) {
+				return "false"
+			}
+			return fmt.Sprintf("true %d", aws.Int64Value(db.PerformanceInsightsRetentionPeriod))
+		},
+		func(src *rds.DBInstance, in *rds.ModifyDBInstanceInput) bool {
+			if aws.BoolValue(src.PerformanceInsightsEnabled) {
+				if src.PerformanceInsightsKMSKeyId == nil {
+					return false
+				}
+				in.PerformanceInsightsKMSKeyId = src.PerformanceInsightsKMSKeyId
+				in.PerformanceInsightsRetentionPeriod = src.PerformanceInsightsRetentionPeriod
+			}
+			in.EnablePerformanceInsights = aws.Bool(aws.BoolValue(src.PerformanceInsightsEnabled))
+			return true
+		},
+	},
+	{
+		// storage autoscaling, it's turned off by setting it to the allocated storage
+		"MaxAllocatedStorage",
+		func(db *rds.DBInstance) string { return fmt.Sprint(aws.Int64Value(db.MaxAllocatedStorage)) },
+		func(src *rds.DBInstance, in *rds.ModifyDBInstanceInput) bool {
+			in.MaxAllocatedStorage = src.MaxAllocatedStorage
+			if in.MaxAllocatedStorage == nil {
+				in.MaxAllocatedStorage = src.AllocatedStorage
+			}
+			return true
+		},
+	},
+	{
+		// vCPU count and threads per core, empty means the instance class defaults
+		"ProcessorFeatures",
+		func(db *rds.DBInstance) string {
+			features := []string{}
+			for _, f := range db.ProcessorFeatures {
+				features = append(features, aws.StringValue(f.Name)+"="+aws.StringValue(f.Value))
+			}
+			sort.Strings(features)
+			return strings.Join(features, commaSep)
+		},
+		func(src *rds.DBInstance, in *rds.ModifyDBInstanceInput) bool {
+			if len(src.ProcessorFeatures) == 0 {
+				in.UseDefaultProcessorFeatures = aws.Bool(true)
+				return true
+			}
+			in.ProcessorFeatures = src.ProcessorFeatures
+			return true
+		},
+	},
+	{
+		"AutoMinorVersionUpgrade",
+		func(db *rds.DBInstance) string { return fmt.Sprint(aws.BoolValue(db.AutoMinorVersionUpgrade)) },
+		func(src *rds.DBInstance, in *rds.ModifyDBInstanceInput) bool {
+			in.AutoMinorVersionUpgrade = src.AutoMinorVersionUpgrade
+			return true
+		},
+	},
+}
+
+// MatchAttributes - bring every attribute in restorableAttrs of `targetName` in line
+// with `source` (Reconcile with only DesiredState.Source set) and return the attributes
+// that still could not be matched
+func (s *SDK) MatchAttributes(source Instance, targetName string) ([]string, error) {
+	_, err := s.Reconcile(targetName, &DesiredState{Source: source.RDSDBInstance})
+	var nc *NotConvergedError
+	if errors.As(err, &nc) {
+		return nc.Fields, nil
+	}
+	return nil, err
+}
+
+// modifyAndMatch - the post-restore step: parameter group and security groups (with the
+// reboot) and everything in restorableAttrs in a single Reconcile, what can't be matched
+// is returned as a *NotConvergedError along with the (usable) restored instance, the
+// events go into `timeline` (if not nil)
+func (s *SDK) modifyAndMatch(source Instance, targetName, dbParGroupName string, vpcSecurityGroups []*string, timeline *EventTimeline) (Instance, error) {
+	i, err := s.Reconcile(targetName, &DesiredState{
+		DBParameterGroupName: dbParGroupName,
+		VpcSecurityGroupIds:  vpcSecurityGroups,
+		Source:               source.RDSDBInstance,
+		Timeline:             timeline,
+	})
+	var nc *NotConvergedError
+	if errors.As(err, &nc) {
+		for _, name := range nc.Fields {
+			s.log.Printf("... MatchAttributes: [%24s] WARNING: could not match %s with %q", targetName, name, source.Name)
+		}
+	}
+	return i, err
+}
@@ -0,0 +1,104 @@
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws/awsutil"
+)
+
+// expectedDiffs - rds.DBInstance fields that are supposed to differ between
+// an instance and it's encrypted copy
+var expectedDiffs = map[string]bool{
+	"AssociatedRoles":                       true, // IAM roles (ie: S3 import) aren't re-associated
+	"AvailabilityZone":                      true, // RDS picks the AZs of a Multi-AZ restore
+	"DBInstanceArn":                         true,
+	"DBInstanceIdentifier":                  true,
+	"DbiResourceId":                         true,
+	"EnhancedMonitoringResourceArn":         true, // derived from DbiResourceId
+	"Endpoint":                              true,
+	"InstanceCreateTime":                    true,
+	"KmsKeyId":                              true,
+	"LatestRestorableTime":                  true,
+	"PerformanceInsightsKMSKeyId":           true,
+	"ReadReplicaDBInstanceIdentifiers":      true, // names differ, topology is checked by the caller
+	"ReadReplicaSourceDBInstanceIdentifier": true,
+	"SecondaryAvailabilityZone":             true,
+	"StorageEncrypted":                      true,
+}
+
+// FieldDiff - a single rds.DBInstance field that differs
+type FieldDiff struct {
+	Field    string
+	A        string
+	B        string
+	Expected bool
+}
+
+// CompareInstances - diffs RDSDBInstance of `a` and `b`
+// field by field, returns all of the differences and an error if any of them is unexpected,
+// so validation can gate the rename step
+func CompareInstances(a, b Instance) ([]FieldDiff, error) {
+	return compareInstances(a, b, expectedDiffs)
+}
+
+// compareInstances - CompareInstances with `expected` instead of expectedDiffs
+func compareInstances(a, b Instance, expected map[string]bool) ([]FieldDiff, error) {
+	if a.RDSDBInstance == nil || b.RDSDBInstance == nil {
+		return nil, fmt.Errorf("ERROR: CompareInstances(%s, %s) needs both instances described", a.Name, b.Name)
+	}
+	va := reflect.ValueOf(a.RDSDBInstance).Elem()
+	vb := reflect.ValueOf(b.RDSDBInstance).Elem()
+
+	diffs := []FieldDiff{}
+	unexpected := []string{}
+	for n := 0; n < va.NumField(); n++ {
+		field := va.Type().Field(n)
+		if field.PkgPath != "" {
+			continue // unexported
+		}
+
+		pa, pb := prettyField(va.Field(n)), prettyField(vb.Field(n))
+		if pa == pb {
+			continue
+		}
+
+		d := FieldDiff{Field: field.Name, A: pa, B: pb, Expected: expected[field.Name]}
+		diffs = append(diffs, d)
+		if !d.Expected {
+			unexpected = append(unexpected, d.Field)
+		}
+	}
+
+	if len(unexpected) > 0 {
+		return diffs, fmt.Errorf("%q vs %q: unexpected differences in: %s", a.Name, b.Name, strings.Join(unexpected, commaSep))
+	}
+	return diffs, nil
+}
+
+// prettyField - comparable representation of a field value, slices are
+// sorted since AWS doesn't guarantee the order of i.e. VpcSecurityGroups
+func prettyField(v reflect.Value) string {
+	if v.Kind() != reflect.Slice {
+		return awsutil.Prettify(v.Interface())
+	}
+
+	items := []string{}
+	for n := 0; n < v.Len(); n++ {
+		items = append(items, awsutil.Prettify(v.Index(n).Interface()))
+	}
+	sort.Strings(items)
+	return "[" + strings.Join(items, commaSep) + "]"
+}
+
+// logDiffs - print the differences, `-` for `a` and `+` for `b`
+func (s *SDK) logDiffs(a, b Instance, diffs []FieldDiff) {
+	s.log.Printf("... CompareInstances: [%24s] vs %q: %d difference(s)", a.Name, b.Name, len(diffs))
+	for _, d := range diffs {
+		tag := "unexpected"
+		if d.Expected {
+			tag = "expected"
+		}
+		s.log.Printf("... CompareInstances: [%24s] %s (%s):\n- %s\n+ %s", a.Name, d.Field, tag, d.A, d.B)
+	}
+}
@@ -0,0 +1,97 @@
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+// processListQuery - every client session, ie: not RDS's own, the server's background
+// threads (replication SQL/IO threads, binlog dumps to replicas, daemons) and the one
+// running this query
+const processListQuery = `
+select
+    ID
+,   USER
+,   HOST
+,   DB
+,   COMMAND
+,   TIME
+from information_schema.processlist
+where USER not in ('rdsadmin', 'event_scheduler', 'system user')
+and COMMAND not in ('Binlog Dump', 'Binlog Dump GTID', 'Daemon')
+and ID != connection_id()
+`
+
+// DrainOptions - how hard to try to get rid of lingering connections
+type DrainOptions struct {
+	Kill     bool          // kill lingering sessions with mysql.rds_kill
+	Deadline time.Duration // keep checking for this long, 0 means check only once
+}
+
+// DrainConnections - runbook steps 5 and 6, block until nobody is connected to the
+// instance (optionally killing whoever is) or until the deadline passes,
+// for now only mysql is supported
+func (s *SDK) DrainConnections(i Instance, rootPass string, opts DrainOptions) error {
+	if i.Engine != "mysql" {
+		return nil
+	}
+
+	if err := i.connect("root", rootPass, "mysql"); err != nil {
+		return err
+	}
+	defer i.DB.Close()
+
+	// otherwise our own idle pool connections would show up in the processlist
+	i.DB.SetMaxOpenConns(1)
+
+	deadline := time.Now().Add(opts.Deadline)
+	for {
+		sessions, err := i.dumpQuery(processListQuery, map[string]interface{}{"ID": nil})
+		if err != nil {
+			return err
+		}
+
+		if len(sessions) == 0 {
+			s.log.Printf("... DrainConnections: [%24s] no connections", i.Name)
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%q still has %d connection(s): %s", i.Name, len(sessions), describeSessions(sessions))
+		}
+
+		s.log.Printf("... DrainConnections: [%24s] waiting for %d connection(s) to go away: %s", i.Name, len(sessions), describeSessions(sessions))
+		if opts.Kill {
+			for _, row := range sessions {
+				s.log.Printf("... DrainConnections: [%24s] killing %s@%s (id: %s)", i.Name, *row["USER"], *row["HOST"], *row["ID"])
+				if _, err := i.DB.Exec("call mysql.rds_kill(?)", *row["ID"]); err != nil {
+					// the session may have gone away on it's own in the meantime
+					s.log.Printf("... DrainConnections: [%24s] can't kill %s: %v", i.Name, *row["ID"], err)
+				}
+			}
+		}
+
+		if err := s.pause(defaultSleep); err != nil {
+			return err
+		}
+	}
+}
+
+// QuiescedSnapshot - GenerateSnapshot, but only once `i` has no connections left
+func (s *SDK) QuiescedSnapshot(i Instance, rootPass string, opts DrainOptions, takeFreshSnap bool, kmsKeyID string) (*rds.DBSnapshot, error) {
+	if err := s.DrainConnections(i, rootPass, opts); err != nil {
+		return nil, err
+	}
+	return s.GenerateSnapshot(i.RDSDBInstance.DBInstanceIdentifier, takeFreshSnap, kmsKeyID)
+}
+
+func describeSessions(sessions map[string]map[string]*string) string {
+	users := []string{}
+	for _, row := range sessions {
+		users = append(users, *row["USER"]+"@"+*row["HOST"])
+	}
+	sort.Strings(users)
+	return strings.Join(users, commaSep)
+}
@@ -0,0 +1,155 @@
+import (
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+// RestoreOverrides - attributes that can't be copied from the source instance when
+// restoring into another region or account, since the groups they reference are
+// regional (and per account), empty values keep the source's
+type RestoreOverrides struct {
+	DBSubnetGroupName    string
+	DBParameterGroupName string
+	OptionGroupName      string
+	VpcSecurityGroupIds  []string
+	AvailabilityZone     string // ignored for MultiAZ, RDS picks one if empty
+
+	// MonitoringRoleArn and PerformanceInsightsKMSKeyId - IAM roles and KMS keys are per
+	// account (and keys regional), if empty enhanced monitoring and Performance Insights
+	// are not enabled on the restored instance but reported as not matched
+	MonitoringRoleArn           string
+	PerformanceInsightsKMSKeyId string
+
+	// CloneParameterGroup - create DBParameterGroupName as a clone of the source's
+	// parameter group (see CloneParameterGroup) instead of expecting it to exist
+	CloneParameterGroup bool
+
+	// Timeline - see DesiredState.Timeline
+	Timeline *EventTimeline
+}
+
+// apply - copy of `src` with the overrides in place, so RestoreInstanceFromSnapshot
+// can do the usual attribute matching for everything else, `foreign` if the restore
+// is in another region or account, where `src`'s role and KMS key ARNs don't exist
+func (o *RestoreOverrides) apply(src Instance, foreign bool) Instance {
+	rdsi := *src.RDSDBInstance
+	rdsi.AvailabilityZone = nil
+	if o.AvailabilityZone != "" {
+		rdsi.AvailabilityZone = aws.String(o.AvailabilityZone)
+	}
+	if foreign {
+		rdsi.MonitoringRoleArn, rdsi.PerformanceInsightsKMSKeyId = nil, nil
+	}
+	if o.MonitoringRoleArn != "" {
+		rdsi.MonitoringRoleArn = aws.String(o.MonitoringRoleArn)
+	}
+	if o.PerformanceInsightsKMSKeyId != "" {
+		rdsi.PerformanceInsightsKMSKeyId = aws.String(o.PerformanceInsightsKMSKeyId)
+	}
+	if o.DBSubnetGroupName != "" {
+		rdsi.DBSubnetGroup = &rds.DBSubnetGroup{DBSubnetGroupName: aws.String(o.DBSubnetGroupName)}
+	}
+	if o.DBParameterGroupName != "" {
+		rdsi.DBParameterGroups = []*rds.DBParameterGroupStatus{{DBParameterGroupName: aws.String(o.DBParameterGroupName)}}
+	}
+	if o.OptionGroupName != "" {
+		rdsi.OptionGroupMemberships = []*rds.OptionGroupMembership{{OptionGroupName: aws.String(o.OptionGroupName)}}
+	}
+	if len(o.VpcSecurityGroupIds) > 0 {
+		rdsi.VpcSecurityGroups = nil
+		for _, id := range o.VpcSecurityGroupIds {
+			rdsi.VpcSecurityGroups = append(rdsi.VpcSecurityGroups, &rds.VpcSecurityGroupMembership{
+				VpcSecurityGroupId: aws.String(id),
+				Status:             aws.String(Active),
+			})
+		}
+	}
+
+	i := src
+	i.RDSDBInstance = &rdsi
+	return i
+}
+
+// CopyToRegion - copy encrypted `snap` into the region `dst` is configured for, re-encrypting
+// it with `kmsKeyID` (KMS keys are regional), `sourceRegion` is the region `snap` lives in
+func (sm *SnapshotManager) CopyToRegion(dst *SDK, snap *rds.DBSnapshot, sourceRegion, kmsKeyID string) (*rds.DBSnapshot, error) {
+	return sm.copyInto(dst, snap, sourceRegion, kmsKeyID)
+}
+
+// ShareWithAccount - allow `accountID` to restore/copy `snap`, for encrypted snapshots the
+// KMS key has to be a customer managed key that is also shared with that account
+func (sm *SnapshotManager) ShareWithAccount(snap *rds.DBSnapshot, accountID string) error {
+	sm.s.log.Printf("... SnapshotManager: [%24s] sharing %q with account %q", aws.StringValue(snap.DBInstanceIdentifier), *snap.DBSnapshotIdentifier, accountID)
+	if _, err := sm.s.svc.ModifyDBSnapshotAttributeWithContext(sm.s.ctx, &rds.ModifyDBSnapshotAttributeInput{
+		DBSnapshotIdentifier: snap.DBSnapshotIdentifier,
+		AttributeName:        aws.String("restore"),
+		ValuesToAdd:          []*string{aws.String(accountID)},
+	}); err != nil {
+		return fmt.Errorf("ERROR: ModifyDBSnapshotAttributeWithContext(%s) failed with: %v", *snap.DBSnapshotIdentifier, err)
+	}
+	return nil
+}
+
+// CopyToAccount - share `snap` with the account `dst` is configured for and copy it there,
+// re-encrypting it with `kmsKeyID` owned by that account so it no longer depends on ours
+func (sm *SnapshotManager) CopyToAccount(dst *SDK, snap *rds.DBSnapshot, accountID, kmsKeyID string) (*rds.DBSnapshot, error) {
+	if err := sm.ShareWithAccount(snap, accountID); err != nil {
+		return nil, err
+	}
+	return sm.copyInto(dst, snap, "", kmsKeyID)
+}
+
+// copyInto - copy `snap` (referenced by ARN) using `dst`'s region and credentials
+func (sm *SnapshotManager) copyInto(dst *SDK, snap *rds.DBSnapshot, sourceRegion, kmsKeyID string) (*rds.DBSnapshot, error) {
+	dsm := dst.NewSnapshotManager(sm.RunID)
+	dsm.WaitTimeout = sm.WaitTimeout
+	id := *snap.DBSnapshotIdentifier
+	existing, err := dsm.describe(id)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		dst.log.Printf("... SnapshotManager: [%24s] %q already copied", aws.StringValue(snap.DBInstanceIdentifier), id)
+		return dsm.waitForSnapshot(id)
+	}
+
+	input := &rds.CopyDBSnapshotInput{
+		SourceDBSnapshotIdentifier: snap.DBSnapshotArn,
+		TargetDBSnapshotIdentifier: aws.String(id),
+		KmsKeyId:                   aws.String(kmsKeyID),
+		Tags:                       dsm.tags(snapRoleCopy),
+	}
+	// the SDK presigns the cross region request when SourceRegion is set
+	if sourceRegion != "" {
+		input.SourceRegion = aws.String(sourceRegion)
+	}
+
+	dst.log.Printf("... SnapshotManager: [%24s] copying %q with KMS key %q", aws.StringValue(snap.DBInstanceIdentifier), *snap.DBSnapshotArn, kmsKeyID)
+	if _, err := dst.svc.CopyDBSnapshotWithContext(dst.ctx, input); err != nil {
+		return nil, fmt.Errorf("ERROR: CopyDBSnapshotWithContext(%s) failed with: %v", *snap.DBSnapshotArn, err)
+	}
+
+	return dsm.waitForSnapshot(id)
+}
+
+// RestoreInstanceElsewhere - restore `snap` (already copied with CopyToRegion or CopyToAccount)
+// using `dst` and match `src`'s attributes, with `ov` supplying the regional ones, ie:
+// for DR rebuilds and account migrations. `dst` can be `s` itself, ie: to restore into
+// a clone of the source's parameter group
+func (s *SDK) RestoreInstanceElsewhere(dst *SDK, src Instance, snap *rds.DBSnapshot, np *NameParser, ov *RestoreOverrides) (Instance, error) {
+	if ov == nil {
+		ov = &RestoreOverrides{}
+	}
+	if ov.CloneParameterGroup && ov.DBParameterGroupName != "" {
+		from, err := parameterGroup(src.RDSDBInstance)
+		if err != nil {
+			return Instance{}, err
+		}
+		if _, err := dst.CloneParameterGroup(ParameterGroupRef{SDK: s, Name: from}, ov.DBParameterGroupName); err != nil {
+			return Instance{}, err
+		}
+	}
+	s.log.Printf("... RestoreInstanceElsewhere: [%24s] restoring %q in the destination region/account", src.Name, *snap.DBSnapshotIdentifier)
+	return dst.restoreInstanceFromSnapshot(ov.apply(src, dst != s), snap, np, ov.Timeline)
+}
@@ -0,0 +1,258 @@
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// fakeMySQL - in-process stand-in for the MySQL server of a fakeRDS instance, it
+// answers exactly the kind of queries the SDK runs: `select version()` and `select
+// <columns> from <table>` (where clauses are ignored), everything that is Exec'd is
+// recorded in execs (with the arguments interpolated) and the RDS procedures
+// rds_kill and rds_set_configuration change the tables they'd change, ie:
+//
+//	f.MySQL("prod-one").AddRow("information_schema.processlist", map[string]string{"ID": "42", ...})
+type fakeMySQL struct {
+	mu      sync.Mutex
+	version string
+	tables  map[string][]map[string]*string
+	execs   []string
+}
+
+// fakeMySQLTables - tables every fakeMySQL starts with
+var fakeMySQLTables = []string{
+	"mysql.user",
+	"mysql.db",
+	"mysql.tables_priv",
+	"mysql.columns_priv",
+	"mysql.procs_priv",
+	"mysql.rds_configuration",
+	"information_schema.processlist",
+}
+
+// MySQL - the MySQL server of `name`, it moves along with the instance when it's renamed,
+// new instances start out with only rdsadmin and the default RDS configuration
+func (f *fakeRDS) MySQL(name string) *fakeMySQL {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	fi, ok := f.instances[name]
+	if !ok {
+		return nil
+	}
+	if fi.mysql == nil {
+		m := &fakeMySQL{version: *fi.db.EngineVersion, tables: make(map[string][]map[string]*string)}
+		for _, table := range fakeMySQLTables {
+			m.tables[table] = []map[string]*string{}
+		}
+		m.addRow("mysql.user", map[string]*string{"Host": aws.String("localhost"), "User": aws.String("rdsadmin")})
+		m.addRow("mysql.rds_configuration", map[string]*string{"name": aws.String(binlogRetentionHours), "value": nil})
+		m.addRow("mysql.rds_configuration", map[string]*string{"name": aws.String("target delay"), "value": aws.String("0")})
+		fi.mysql = m
+	}
+	return fi.mysql
+}
+
+// AddRow - seed a row of `table`, columns it doesn't have are NULL
+func (m *fakeMySQL) AddRow(table string, row map[string]string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	r := make(map[string]*string)
+	for col, value := range row {
+		r[col] = aws.String(value)
+	}
+	m.addRow(table, r)
+}
+
+func (m *fakeMySQL) addRow(table string, row map[string]*string) {
+	m.tables[table] = append(m.tables[table], row)
+}
+
+// Execs - everything Exec'd so far
+func (m *fakeMySQL) Execs() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]string{}, m.execs...)
+}
+
+func (m *fakeMySQL) query(query string) (driver.Rows, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if strings.TrimSpace(query) == "select version()" {
+		return &fakeMySQLRows{columns: []string{"version()"}, rows: [][]driver.Value{{[]byte(m.version)}}}, nil
+	}
+
+	// select <col>, <col>, ... from <table> ...
+	fields := strings.Fields(query)
+	from := -1
+	for n, field := range fields {
+		if field == "from" {
+			from = n
+			break
+		}
+	}
+	if len(fields) == 0 || fields[0] != "select" || from < 0 || from == len(fields)-1 {
+		return nil, fmt.Errorf("fakeMySQL: can't parse query %q", query)
+	}
+	table, ok := m.tables[fields[from+1]]
+	if !ok {
+		return nil, fmt.Errorf("fakeMySQL: table %q doesn't exist", fields[from+1])
+	}
+
+	r := &fakeMySQLRows{}
+	for _, col := range strings.Split(strings.Join(fields[1:from], " "), ",") {
+		r.columns = append(r.columns, strings.TrimSpace(col))
+	}
+	for _, row := range table {
+		values := []driver.Value{}
+		for _, col := range r.columns {
+			if v := row[col]; v != nil {
+				values = append(values, []byte(*v))
+			} else {
+				values = append(values, nil)
+			}
+		}
+		r.rows = append(r.rows, values)
+	}
+	return r, nil
+}
+
+func (m *fakeMySQL) exec(query string, args []driver.NamedValue) (driver.Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	values := []*string{}
+	stmt := query
+	for _, arg := range args {
+		var v *string
+		literal := "NULL"
+		if arg.Value != nil {
+			v = aws.String(fmt.Sprint(arg.Value))
+			literal = "'" + quote(*v) + "'"
+		}
+		values = append(values, v)
+		stmt = strings.Replace(stmt, "?", literal, 1)
+	}
+	m.execs = append(m.execs, stmt)
+
+	switch {
+	case strings.HasPrefix(query, "call mysql.rds_kill("):
+		m.deleteRows("information_schema.processlist", "ID", values[0])
+	case strings.HasPrefix(query, "call mysql.rds_set_configuration("):
+		m.deleteRows("mysql.rds_configuration", "name", values[0])
+		m.addRow("mysql.rds_configuration", map[string]*string{"name": values[0], "value": values[1]})
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (m *fakeMySQL) deleteRows(table, col string, value *string) {
+	rows := []map[string]*string{}
+	for _, row := range m.tables[table] {
+		if row[col] == nil || value == nil || *row[col] != *value {
+			rows = append(rows, row)
+		}
+	}
+	m.tables[table] = rows
+}
+
+type fakeMySQLRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeMySQLRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeMySQLRows) Close() error {
+	return nil
+}
+
+func (r *fakeMySQLRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// fakeMySQLDriver - database/sql driver connecting to the fakeMySQL of the fakeRDS
+// instance the endpoint address in the DSN belongs to
+type fakeMySQLDriver struct {
+	mu sync.Mutex
+	f  *fakeRDS
+}
+
+var fakeMySQLDrv = &fakeMySQLDriver{}
+
+func init() {
+	sql.Register("fakemysql", fakeMySQLDrv)
+}
+
+// useFakeMySQL - connect to the instances of `f` instead of real MySQL servers
+func useFakeMySQL(t *testing.T, f *fakeRDS) {
+	drv := mysqlDriver
+	mysqlDriver = "fakemysql"
+	fakeMySQLDrv.mu.Lock()
+	fakeMySQLDrv.f = f
+	fakeMySQLDrv.mu.Unlock()
+
+	t.Cleanup(func() {
+		mysqlDriver = drv
+		fakeMySQLDrv.mu.Lock()
+		fakeMySQLDrv.f = nil
+		fakeMySQLDrv.mu.Unlock()
+	})
+}
+
+// Open - `dsn` is user:pass@tcp(host:port)/schema?params, see Instance.connect
+func (d *fakeMySQLDriver) Open(dsn string) (driver.Conn, error) {
+	d.mu.Lock()
+	f := d.f
+	d.mu.Unlock()
+
+	addr := dsn[strings.Index(dsn, "@tcp(")+len("@tcp("):]
+	host := addr[:strings.Index(addr, ":")]
+	if f == nil || !strings.HasSuffix(host, fakeDNSSuffix) {
+		return nil, fmt.Errorf("fakeMySQL: dial tcp: lookup %s: no such host", host)
+	}
+	m := f.MySQL(strings.TrimSuffix(host, fakeDNSSuffix))
+	if m == nil {
+		return nil, fmt.Errorf("fakeMySQL: dial tcp: lookup %s: no such host", host)
+	}
+	return &fakeMySQLConn{m: m}, nil
+}
+
+type fakeMySQLConn struct {
+	m *fakeMySQL
+}
+
+func (c *fakeMySQLConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("fakeMySQL: prepared statements are not supported: %q", query)
+}
+
+func (c *fakeMySQLConn) Close() error {
+	return nil
+}
+
+func (c *fakeMySQLConn) Begin() (driver.Tx, error) {
+	return nil, fmt.Errorf("fakeMySQL: transactions are not supported")
+}
+
+func (c *fakeMySQLConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.m.query(query)
+}
+
+func (c *fakeMySQLConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	return c.m.exec(query, args)
+}
@@ -0,0 +1,923 @@
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/rds/rdsiface"
+)
+
+const (
+	fakeRegion    = "us-east-1"
+	fakeAccount   = "123456789012"
+	fakeDNSSuffix = ".abcdefghijkl." + fakeRegion + ".rds.amazonaws.com"
+
+	inSync        = "in-sync"
+	applying      = "applying"
+	creating      = "creating"
+	backingUp     = "backing-up"
+	modifying     = "modifying"
+	rebooting     = "rebooting"
+	renaming      = "renaming"
+	deleting      = "deleting"
+	snapAvailable = "available"
+)
+
+// fakeRDS - in-process stand-in for the RDS API used by SDK.svc, so the
+// restore/modify/reboot flows can be exercised without live AWS, ie:
+//
+//	s := &SDK{svc: newFakeRDS(), ctx: context.Background(), log: log.New(os.Stderr, "", 0)}
+//
+// Every mutating call queues the status transitions RDS would go through
+// (creating -> backing-up -> available, parameter group applying -> pending-reboot
+// -> in-sync on reboot, ...) and every Describe call moves each instance/snapshot
+// one transition forward. Calls not implemented here panic via the embedded
+// nil interface, which is what you want in a test.
+type fakeRDS struct {
+	rdsiface.RDSAPI
+
+	mu        sync.Mutex
+	instances map[string]*fakeInstance
+	snapshots map[string]*fakeSnapshot
+	events    []*rds.Event
+
+	parGroups    map[string]*rds.DBParameterGroup
+	parameters   map[string]map[string]*rds.Parameter // user-modified, by group
+	optionGroups map[string]*rds.OptionGroup
+}
+
+type fakeInstance struct {
+	db      *rds.DBInstance
+	pending []func(f *fakeRDS)
+	mysql   *fakeMySQL // see fakeRDS.MySQL
+}
+
+type fakeSnapshot struct {
+	snap    *rds.DBSnapshot
+	pending []func()
+}
+
+func newFakeRDS() *fakeRDS {
+	return &fakeRDS{
+		instances:    make(map[string]*fakeInstance),
+		snapshots:    make(map[string]*fakeSnapshot),
+		parGroups:    make(map[string]*rds.DBParameterGroup),
+		parameters:   make(map[string]map[string]*rds.Parameter),
+		optionGroups: make(map[string]*rds.OptionGroup),
+	}
+}
+
+// AddInstance - seed an available instance, `db` only needs DBInstanceIdentifier,
+// sane defaults are filled in for everything the SDK dereferences
+func (f *fakeRDS) AddInstance(db *rds.DBInstance) *rds.DBInstance {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.fillDefaults(db)
+	f.instances[*db.DBInstanceIdentifier] = &fakeInstance{db: db}
+	return db
+}
+
+// AddParameterGroup - seed a custom DB parameter group, the "default.<family>"
+// ones always exist
+func (f *fakeRDS) AddParameterGroup(name, family string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.parGroups[name] = &rds.DBParameterGroup{
+		DBParameterGroupName:   aws.String(name),
+		DBParameterGroupFamily: aws.String(family),
+	}
+}
+
+// SetParameter - seed a user-modified parameter of `group`
+func (f *fakeRDS) SetParameter(group, name, value, applyType string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.setParameter(group, &rds.Parameter{
+		ParameterName:  aws.String(name),
+		ParameterValue: aws.String(value),
+		ApplyType:      aws.String(applyType),
+	})
+}
+
+func (f *fakeRDS) setParameter(group string, p *rds.Parameter) {
+	if f.parameters[group] == nil {
+		f.parameters[group] = make(map[string]*rds.Parameter)
+	}
+	f.parameters[group][*p.ParameterName] = &rds.Parameter{
+		ParameterName:  p.ParameterName,
+		ParameterValue: p.ParameterValue,
+		ApplyType:      p.ApplyType,
+		Source:         aws.String("user"),
+	}
+}
+
+// AddOptionGroup - seed a custom option group, the "default:<engine>-<major>"
+// ones always exist
+func (f *fakeRDS) AddOptionGroup(name, engine, majorVersion string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.optionGroups[name] = &rds.OptionGroup{
+		OptionGroupName:    aws.String(name),
+		EngineName:         aws.String(engine),
+		MajorEngineVersion: aws.String(majorVersion),
+	}
+}
+
+// fakeFamily - ie: mysql 5.7.26 -> mysql5.7
+func fakeFamily(engine, version string) string {
+	return engine + fakeMajor(version)
+}
+
+// fakeMajor - ie: 5.7.26 -> 5.7
+func fakeMajor(version string) string {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) > 2 {
+		parts = parts[:2]
+	}
+	return strings.Join(parts, ".")
+}
+
+func (f *fakeRDS) fillDefaults(db *rds.DBInstance) {
+	name := *db.DBInstanceIdentifier
+	setDefault := func(p **string, v string) {
+		if *p == nil {
+			*p = aws.String(v)
+		}
+	}
+	setDefault(&db.DBInstanceStatus, Available)
+	setDefault(&db.Engine, "mysql")
+	setDefault(&db.EngineVersion, "5.7.26")
+	setDefault(&db.DBInstanceClass, "db.r5.large")
+	setDefault(&db.StorageType, "gp2")
+	setDefault(&db.AvailabilityZone, fakeRegion+"a")
+	db.DBInstanceArn = aws.String(fakeARN("db", name))
+	if db.MultiAZ == nil {
+		db.MultiAZ = aws.Bool(false)
+	}
+	if aws.BoolValue(db.MultiAZ) {
+		// standby has to be somewhere else for RebootInstance to fail over to it
+		setDefault(&db.SecondaryAvailabilityZone, fakeStandbyAZ(*db.AvailabilityZone))
+	}
+	if db.StorageEncrypted == nil {
+		db.StorageEncrypted = aws.Bool(false)
+	}
+	if db.AllocatedStorage == nil {
+		db.AllocatedStorage = aws.Int64(100)
+	}
+	if db.DbInstancePort == nil {
+		db.DbInstancePort = aws.Int64(0)
+	}
+	if db.InstanceCreateTime == nil {
+		db.InstanceCreateTime = aws.Time(time.Now())
+	}
+	if db.DBSubnetGroup == nil {
+		db.DBSubnetGroup = &rds.DBSubnetGroup{DBSubnetGroupName: aws.String("default")}
+	}
+	if len(db.DBParameterGroups) == 0 {
+		db.DBParameterGroups = []*rds.DBParameterGroupStatus{{
+			DBParameterGroupName: aws.String("default." + fakeFamily(*db.Engine, *db.EngineVersion)),
+			ParameterApplyStatus: aws.String(inSync),
+		}}
+	}
+	if len(db.OptionGroupMemberships) == 0 {
+		db.OptionGroupMemberships = []*rds.OptionGroupMembership{{
+			OptionGroupName: aws.String("default:" + *db.Engine + "-" + strings.Replace(fakeMajor(*db.EngineVersion), ".", "-", -1)),
+			Status:          aws.String("in-sync"),
+		}}
+	}
+	db.Endpoint = &rds.Endpoint{Address: aws.String(name + fakeDNSSuffix), Port: aws.Int64(3306)}
+}
+
+// fakeStandbyAZ - ie: us-east-1a -> us-east-1b
+func fakeStandbyAZ(az string) string {
+	if az == fakeRegion+"b" {
+		return fakeRegion + "a"
+	}
+	return fakeRegion + "b"
+}
+
+func fakeARN(kind, name string) string {
+	return fmt.Sprintf("arn:aws:rds:%s:%s:%s:%s", fakeRegion, fakeAccount, kind, name)
+}
+
+func notFound(name string) error {
+	return awserr.New(rds.ErrCodeDBInstanceNotFoundFault, fmt.Sprintf("DBInstance %s not found.", name), nil)
+}
+
+// setStatus - queue transition to `status`
+func setStatus(status string) func(f *fakeRDS, db *rds.DBInstance) {
+	return func(f *fakeRDS, db *rds.DBInstance) { db.DBInstanceStatus = aws.String(status) }
+}
+
+// queue - schedule transitions for `name`, each one is applied on a subsequent Describe
+func (f *fakeRDS) queue(name string, steps ...func(f *fakeRDS, db *rds.DBInstance)) {
+	fi := f.instances[name]
+	for _, step := range steps {
+		step := step
+		fi.pending = append(fi.pending, func(f *fakeRDS) {
+			step(f, fi.db)
+		})
+	}
+}
+
+// tick - apply the next pending transition of every instance and snapshot
+func (f *fakeRDS) tick() {
+	names := []string{}
+	for name := range f.instances {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fi, ok := f.instances[name]
+		if !ok || len(fi.pending) == 0 {
+			continue
+		}
+		next := fi.pending[0]
+		fi.pending = fi.pending[1:]
+		next(f)
+	}
+
+	for _, fs := range f.snapshots {
+		if len(fs.pending) == 0 {
+			continue
+		}
+		next := fs.pending[0]
+		fs.pending = fs.pending[1:]
+		next()
+	}
+}
+
+func (f *fakeRDS) event(name, msg string) {
+	f.events = append(f.events, &rds.Event{
+		Date:             aws.Time(time.Now()),
+		Message:          aws.String(msg),
+		SourceIdentifier: aws.String(name),
+		SourceType:       aws.String(rds.SourceTypeDbInstance),
+	})
+}
+
+func (f *fakeRDS) DescribeDBInstancesWithContext(ctx aws.Context, in *rds.DescribeDBInstancesInput, opts ...request.Option) (*rds.DescribeDBInstancesOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.tick()
+
+	out := &rds.DescribeDBInstancesOutput{}
+	if name := aws.StringValue(in.DBInstanceIdentifier); name != "" {
+		fi, ok := f.instances[name]
+		if !ok {
+			return nil, notFound(name)
+		}
+		out.DBInstances = append(out.DBInstances, copyDBInstance(fi.db))
+		return out, nil
+	}
+
+	names := []string{}
+	for name := range f.instances {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		out.DBInstances = append(out.DBInstances, copyDBInstance(f.instances[name].db))
+	}
+	return out, nil
+}
+
+func (f *fakeRDS) DescribeDBInstancesPagesWithContext(ctx aws.Context, in *rds.DescribeDBInstancesInput, fn func(*rds.DescribeDBInstancesOutput, bool) bool, opts ...request.Option) error {
+	out, err := f.DescribeDBInstancesWithContext(ctx, in, opts...)
+	if err != nil {
+		return err
+	}
+	fn(out, true)
+	return nil
+}
+
+func (f *fakeRDS) RestoreDBInstanceFromDBSnapshotWithContext(ctx aws.Context, in *rds.RestoreDBInstanceFromDBSnapshotInput, opts ...request.Option) (*rds.RestoreDBInstanceFromDBSnapshotOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.StringValue(in.DBInstanceIdentifier)
+	if _, ok := f.instances[name]; ok {
+		return nil, awserr.New(rds.ErrCodeDBInstanceAlreadyExistsFault, "DB instance already exists", nil)
+	}
+	fs, ok := f.snapshots[aws.StringValue(in.DBSnapshotIdentifier)]
+	if !ok {
+		return nil, awserr.New(rds.ErrCodeDBSnapshotNotFoundFault, "DBSnapshot not found", nil)
+	}
+
+	db := &rds.DBInstance{
+		AutoMinorVersionUpgrade:      in.AutoMinorVersionUpgrade,
+		AvailabilityZone:             in.AvailabilityZone,
+		CopyTagsToSnapshot:           in.CopyTagsToSnapshot,
+		DBInstanceClass:              in.DBInstanceClass,
+		DBInstanceIdentifier:         in.DBInstanceIdentifier,
+		DBInstanceStatus:             aws.String(creating),
+		DBSubnetGroup:                &rds.DBSubnetGroup{DBSubnetGroupName: in.DBSubnetGroupName},
+		EnabledCloudwatchLogsExports: in.EnableCloudwatchLogsExports,
+		Engine:                       fs.snap.Engine,
+		EngineVersion:                fs.snap.EngineVersion,
+		Iops:                         in.Iops,
+		KmsKeyId:                     fs.snap.KmsKeyId,
+		MultiAZ:                      in.MultiAZ,
+		PubliclyAccessible:           in.PubliclyAccessible,
+		StorageEncrypted:             fs.snap.Encrypted,
+		StorageType:                  in.StorageType,
+	}
+	if in.OptionGroupName != nil {
+		db.OptionGroupMemberships = []*rds.OptionGroupMembership{{OptionGroupName: in.OptionGroupName, Status: aws.String("in-sync")}}
+	}
+	f.fillDefaults(db)
+	db.DBInstanceStatus = aws.String(creating)
+
+	f.instances[name] = &fakeInstance{db: db}
+	f.queue(name, setStatus(creating), setStatus(backingUp), setStatus(Available))
+	f.event(name, "DB instance restored")
+
+	return &rds.RestoreDBInstanceFromDBSnapshotOutput{DBInstance: copyDBInstance(db)}, nil
+}
+
+func (f *fakeRDS) CreateDBInstanceReadReplicaWithContext(ctx aws.Context, in *rds.CreateDBInstanceReadReplicaInput, opts ...request.Option) (*rds.CreateDBInstanceReadReplicaOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.StringValue(in.DBInstanceIdentifier)
+	if _, ok := f.instances[name]; ok {
+		return nil, awserr.New(rds.ErrCodeDBInstanceAlreadyExistsFault, "DB instance already exists", nil)
+	}
+	src, ok := f.instances[aws.StringValue(in.SourceDBInstanceIdentifier)]
+	if !ok {
+		return nil, notFound(aws.StringValue(in.SourceDBInstanceIdentifier))
+	}
+
+	db := &rds.DBInstance{
+		AutoMinorVersionUpgrade:               in.AutoMinorVersionUpgrade,
+		AvailabilityZone:                      in.AvailabilityZone,
+		CopyTagsToSnapshot:                    in.CopyTagsToSnapshot,
+		DBInstanceClass:                       in.DBInstanceClass,
+		DBInstanceIdentifier:                  in.DBInstanceIdentifier,
+		DbInstancePort:                        in.Port,
+		EnabledCloudwatchLogsExports:          in.EnableCloudwatchLogsExports,
+		Engine:                                src.db.Engine,
+		EngineVersion:                         src.db.EngineVersion,
+		Iops:                                  in.Iops,
+		KmsKeyId:                              src.db.KmsKeyId,
+		MonitoringInterval:                    in.MonitoringInterval,
+		MonitoringRoleArn:                     in.MonitoringRoleArn,
+		MultiAZ:                               in.MultiAZ,
+		PerformanceInsightsEnabled:            in.EnablePerformanceInsights,
+		PerformanceInsightsKMSKeyId:           in.PerformanceInsightsKMSKeyId,
+		PubliclyAccessible:                    in.PubliclyAccessible,
+		ReadReplicaSourceDBInstanceIdentifier: in.SourceDBInstanceIdentifier,
+		StorageEncrypted:                      src.db.StorageEncrypted,
+		StorageType:                           in.StorageType,
+	}
+	if in.OptionGroupName != nil {
+		db.OptionGroupMemberships = []*rds.OptionGroupMembership{{OptionGroupName: in.OptionGroupName, Status: aws.String("in-sync")}}
+	}
+	f.fillDefaults(db)
+	db.DBInstanceStatus = aws.String(creating)
+
+	f.instances[name] = &fakeInstance{db: db}
+	src.db.ReadReplicaDBInstanceIdentifiers = append(src.db.ReadReplicaDBInstanceIdentifiers, aws.String(name))
+	f.queue(name, setStatus(creating), setStatus(backingUp), setStatus(Available))
+	f.event(name, "DB instance created")
+
+	return &rds.CreateDBInstanceReadReplicaOutput{DBInstance: copyDBInstance(db)}, nil
+}
+
+func (f *fakeRDS) ModifyDBInstanceWithContext(ctx aws.Context, in *rds.ModifyDBInstanceInput, opts ...request.Option) (*rds.ModifyDBInstanceOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.StringValue(in.DBInstanceIdentifier)
+	fi, ok := f.instances[name]
+	if !ok {
+		return nil, notFound(name)
+	}
+	if s := aws.StringValue(fi.db.DBInstanceStatus); s != Available {
+		return nil, awserr.New(rds.ErrCodeInvalidDBInstanceStateFault, fmt.Sprintf("DB instance %s is in %q state", name, s), nil)
+	}
+
+	steps := []func(f *fakeRDS, db *rds.DBInstance){setStatus(modifying)}
+	if in.DBParameterGroupName != nil {
+		group := in.DBParameterGroupName
+		steps = append(steps, func(f *fakeRDS, db *rds.DBInstance) {
+			db.DBParameterGroups = []*rds.DBParameterGroupStatus{{DBParameterGroupName: group, ParameterApplyStatus: aws.String(applying)}}
+		})
+	}
+	if in.VpcSecurityGroupIds != nil {
+		sgs := []*rds.VpcSecurityGroupMembership{}
+		for _, id := range in.VpcSecurityGroupIds {
+			sgs = append(sgs, &rds.VpcSecurityGroupMembership{VpcSecurityGroupId: id, Status: aws.String(Active)})
+		}
+		steps = append(steps, func(f *fakeRDS, db *rds.DBInstance) { db.VpcSecurityGroups = sgs })
+	}
+	steps = append(steps, func(f *fakeRDS, db *rds.DBInstance) {
+		applyModify(db, in)
+		db.DBInstanceStatus = aws.String(Available)
+		for _, g := range db.DBParameterGroups {
+			if aws.StringValue(g.ParameterApplyStatus) == applying {
+				g.ParameterApplyStatus = aws.String(pendingReboot)
+				f.event(aws.StringValue(db.DBInstanceIdentifier), "Updated to use DBParameterGroup "+aws.StringValue(g.DBParameterGroupName))
+			}
+		}
+	})
+	if newName := aws.StringValue(in.NewDBInstanceIdentifier); newName != "" {
+		if _, ok := f.instances[newName]; ok {
+			return nil, awserr.New(rds.ErrCodeDBInstanceAlreadyExistsFault, "DB instance already exists", nil)
+		}
+		steps = append(steps[:1], setStatus(renaming), func(f *fakeRDS, db *rds.DBInstance) {
+			f.rename(name, newName)
+		}, setStatus(Available))
+	}
+
+	f.queue(name, steps...)
+	return &rds.ModifyDBInstanceOutput{DBInstance: copyDBInstance(fi.db)}, nil
+}
+
+// applyModify - the attributes that just change in place, without a status transition
+func applyModify(db *rds.DBInstance, in *rds.ModifyDBInstanceInput) {
+	if in.BackupRetentionPeriod != nil {
+		db.BackupRetentionPeriod = in.BackupRetentionPeriod
+	}
+	if in.PreferredBackupWindow != nil {
+		db.PreferredBackupWindow = in.PreferredBackupWindow
+	}
+	if in.PreferredMaintenanceWindow != nil {
+		db.PreferredMaintenanceWindow = in.PreferredMaintenanceWindow
+	}
+	if in.DeletionProtection != nil {
+		db.DeletionProtection = in.DeletionProtection
+	}
+	if in.MonitoringInterval != nil {
+		db.MonitoringInterval = in.MonitoringInterval
+	}
+	if in.MonitoringRoleArn != nil {
+		db.MonitoringRoleArn = in.MonitoringRoleArn
+	}
+	if in.MultiAZ != nil {
+		db.MultiAZ = in.MultiAZ
+		db.SecondaryAvailabilityZone = nil
+		if aws.BoolValue(db.MultiAZ) {
+			db.SecondaryAvailabilityZone = aws.String(fakeStandbyAZ(aws.StringValue(db.AvailabilityZone)))
+		}
+	}
+	if in.DBInstanceClass != nil {
+		db.DBInstanceClass = in.DBInstanceClass
+	}
+	if in.MaxAllocatedStorage != nil {
+		db.MaxAllocatedStorage = in.MaxAllocatedStorage
+	}
+	if in.ProcessorFeatures != nil {
+		db.ProcessorFeatures = in.ProcessorFeatures
+	}
+	if aws.BoolValue(in.UseDefaultProcessorFeatures) {
+		db.ProcessorFeatures = nil
+	}
+	if in.OptionGroupName != nil {
+		db.OptionGroupMemberships = []*rds.OptionGroupMembership{{OptionGroupName: in.OptionGroupName, Status: aws.String("in-sync")}}
+	}
+}
+
+// rename - move `from` to `to` along with it's endpoint and replica references
+func (f *fakeRDS) rename(from, to string) {
+	fi := f.instances[from]
+	delete(f.instances, from)
+	f.instances[to] = fi
+
+	fi.db.DBInstanceIdentifier = aws.String(to)
+	fi.db.DBInstanceArn = aws.String(fakeARN("db", to))
+	fi.db.Endpoint.Address = aws.String(to + fakeDNSSuffix)
+
+	for _, other := range f.instances {
+		if aws.StringValue(other.db.ReadReplicaSourceDBInstanceIdentifier) == from {
+			other.db.ReadReplicaSourceDBInstanceIdentifier = aws.String(to)
+		}
+		for n, replica := range other.db.ReadReplicaDBInstanceIdentifiers {
+			if *replica == from {
+				other.db.ReadReplicaDBInstanceIdentifiers[n] = aws.String(to)
+			}
+		}
+	}
+	f.event(to, fmt.Sprintf("Renamed instance from %s to %s", from, to))
+}
+
+func (f *fakeRDS) RebootDBInstanceWithContext(ctx aws.Context, in *rds.RebootDBInstanceInput, opts ...request.Option) (*rds.RebootDBInstanceOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.StringValue(in.DBInstanceIdentifier)
+	fi, ok := f.instances[name]
+	if !ok {
+		return nil, notFound(name)
+	}
+	if s := aws.StringValue(fi.db.DBInstanceStatus); s != Available {
+		return nil, awserr.New(rds.ErrCodeInvalidDBInstanceStateFault, fmt.Sprintf("DB instance %s is in %q state", name, s), nil)
+	}
+
+	failover := aws.BoolValue(in.ForceFailover)
+	if failover && !aws.BoolValue(fi.db.MultiAZ) {
+		return nil, awserr.New(rds.ErrCodeInvalidDBInstanceStateFault, "Cannot failover a Single-AZ instance", nil)
+	}
+
+	fi.db.DBInstanceStatus = aws.String(rebooting)
+	if failover {
+		f.event(name, "Multi-AZ instance failover started")
+	}
+	f.queue(name, setStatus(rebooting), func(f *fakeRDS, db *rds.DBInstance) {
+		for _, g := range db.DBParameterGroups {
+			if aws.StringValue(g.ParameterApplyStatus) == pendingReboot {
+				g.ParameterApplyStatus = aws.String(inSync)
+			}
+		}
+		if failover {
+			db.AvailabilityZone, db.SecondaryAvailabilityZone = db.SecondaryAvailabilityZone, db.AvailabilityZone
+			f.event(name, "Multi-AZ instance failover completed")
+		}
+		db.DBInstanceStatus = aws.String(Available)
+		f.event(name, "DB instance restarted")
+	})
+
+	return &rds.RebootDBInstanceOutput{DBInstance: copyDBInstance(fi.db)}, nil
+}
+
+func (f *fakeRDS) DeleteDBInstanceWithContext(ctx aws.Context, in *rds.DeleteDBInstanceInput, opts ...request.Option) (*rds.DeleteDBInstanceOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.StringValue(in.DBInstanceIdentifier)
+	fi, ok := f.instances[name]
+	if !ok {
+		return nil, notFound(name)
+	}
+
+	fi.db.DBInstanceStatus = aws.String(deleting)
+	f.queue(name, func(f *fakeRDS, db *rds.DBInstance) {
+		delete(f.instances, name)
+		for _, other := range f.instances {
+			for n, replica := range other.db.ReadReplicaDBInstanceIdentifiers {
+				if *replica == name {
+					other.db.ReadReplicaDBInstanceIdentifiers = append(other.db.ReadReplicaDBInstanceIdentifiers[:n], other.db.ReadReplicaDBInstanceIdentifiers[n+1:]...)
+					break
+				}
+			}
+		}
+	})
+
+	return &rds.DeleteDBInstanceOutput{DBInstance: copyDBInstance(fi.db)}, nil
+}
+
+func (f *fakeRDS) CreateDBSnapshotWithContext(ctx aws.Context, in *rds.CreateDBSnapshotInput, opts ...request.Option) (*rds.CreateDBSnapshotOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.StringValue(in.DBInstanceIdentifier)
+	fi, ok := f.instances[name]
+	if !ok {
+		return nil, notFound(name)
+	}
+
+	snap := &rds.DBSnapshot{
+		AllocatedStorage:     fi.db.AllocatedStorage,
+		DBInstanceIdentifier: in.DBInstanceIdentifier,
+		DBSnapshotArn:        aws.String(fakeARN("snapshot", aws.StringValue(in.DBSnapshotIdentifier))),
+		DBSnapshotIdentifier: in.DBSnapshotIdentifier,
+		Encrypted:            fi.db.StorageEncrypted,
+		Engine:               fi.db.Engine,
+		EngineVersion:        fi.db.EngineVersion,
+		KmsKeyId:             fi.db.KmsKeyId,
+		PercentProgress:      aws.Int64(0),
+		SnapshotCreateTime:   aws.Time(time.Now()),
+		SnapshotType:         aws.String("manual"),
+		Status:               aws.String(creating),
+		TagList:              in.Tags,
+	}
+	f.addSnapshot(snap)
+	return &rds.CreateDBSnapshotOutput{DBSnapshot: snap}, nil
+}
+
+func (f *fakeRDS) CopyDBSnapshotWithContext(ctx aws.Context, in *rds.CopyDBSnapshotInput, opts ...request.Option) (*rds.CopyDBSnapshotOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// source can be an ARN
+	srcID := aws.StringValue(in.SourceDBSnapshotIdentifier)
+	srcID = srcID[strings.LastIndex(srcID, ":")+1:]
+	src, ok := f.snapshots[srcID]
+	if !ok {
+		return nil, awserr.New(rds.ErrCodeDBSnapshotNotFoundFault, "DBSnapshot not found", nil)
+	}
+
+	snap := *src.snap
+	snap.DBSnapshotIdentifier = in.TargetDBSnapshotIdentifier
+	snap.DBSnapshotArn = aws.String(fakeARN("snapshot", aws.StringValue(in.TargetDBSnapshotIdentifier)))
+	snap.SourceDBSnapshotIdentifier = src.snap.DBSnapshotArn
+	snap.PercentProgress = aws.Int64(0)
+	snap.Status = aws.String(creating)
+	snap.TagList = in.Tags
+	if in.KmsKeyId != nil {
+		snap.Encrypted = aws.Bool(true)
+		snap.KmsKeyId = in.KmsKeyId
+	}
+	f.addSnapshot(&snap)
+	return &rds.CopyDBSnapshotOutput{DBSnapshot: &snap}, nil
+}
+
+// addSnapshot - snapshots progress 0 -> 50 -> 100% and become available
+func (f *fakeRDS) addSnapshot(snap *rds.DBSnapshot) {
+	fs := &fakeSnapshot{snap: snap}
+	fs.pending = []func(){
+		func() { snap.PercentProgress = aws.Int64(50) },
+		func() {
+			snap.PercentProgress = aws.Int64(100)
+			snap.Status = aws.String(snapAvailable)
+		},
+	}
+	f.snapshots[*snap.DBSnapshotIdentifier] = fs
+}
+
+func (f *fakeRDS) DescribeDBSnapshotsWithContext(ctx aws.Context, in *rds.DescribeDBSnapshotsInput, opts ...request.Option) (*rds.DescribeDBSnapshotsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.tick()
+
+	out := &rds.DescribeDBSnapshotsOutput{}
+	ids := []string{}
+	for id := range f.snapshots {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		snap := f.snapshots[id].snap
+		if want := aws.StringValue(in.DBSnapshotIdentifier); want != "" && want != id {
+			continue
+		}
+		if want := aws.StringValue(in.DBInstanceIdentifier); want != "" && want != aws.StringValue(snap.DBInstanceIdentifier) {
+			continue
+		}
+		if want := aws.StringValue(in.SnapshotType); want != "" && want != aws.StringValue(snap.SnapshotType) {
+			continue
+		}
+		c := *snap
+		out.DBSnapshots = append(out.DBSnapshots, &c)
+	}
+	if aws.StringValue(in.DBSnapshotIdentifier) != "" && len(out.DBSnapshots) == 0 {
+		return nil, awserr.New(rds.ErrCodeDBSnapshotNotFoundFault, "DBSnapshot not found", nil)
+	}
+	return out, nil
+}
+
+func (f *fakeRDS) DescribeDBSnapshotsPagesWithContext(ctx aws.Context, in *rds.DescribeDBSnapshotsInput, fn func(*rds.DescribeDBSnapshotsOutput, bool) bool, opts ...request.Option) error {
+	out, err := f.DescribeDBSnapshotsWithContext(ctx, in, opts...)
+	if err != nil {
+		return err
+	}
+	fn(out, true)
+	return nil
+}
+
+func (f *fakeRDS) DeleteDBSnapshotWithContext(ctx aws.Context, in *rds.DeleteDBSnapshotInput, opts ...request.Option) (*rds.DeleteDBSnapshotOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	id := aws.StringValue(in.DBSnapshotIdentifier)
+	fs, ok := f.snapshots[id]
+	if !ok {
+		return nil, awserr.New(rds.ErrCodeDBSnapshotNotFoundFault, "DBSnapshot not found", nil)
+	}
+	delete(f.snapshots, id)
+	return &rds.DeleteDBSnapshotOutput{DBSnapshot: fs.snap}, nil
+}
+
+func (f *fakeRDS) ListTagsForResourceWithContext(ctx aws.Context, in *rds.ListTagsForResourceInput, opts ...request.Option) (*rds.ListTagsForResourceOutput, error) {
+	return &rds.ListTagsForResourceOutput{}, nil
+}
+
+func (f *fakeRDS) DescribeEventsWithContext(ctx aws.Context, in *rds.DescribeEventsInput, opts ...request.Option) (*rds.DescribeEventsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	out := &rds.DescribeEventsOutput{}
+	for _, e := range f.events {
+		if in.SourceIdentifier != nil && *in.SourceIdentifier != *e.SourceIdentifier {
+			continue
+		}
+		if in.StartTime != nil && e.Date.Before(*in.StartTime) {
+			continue
+		}
+		out.Events = append(out.Events, e)
+	}
+	return out, nil
+}
+
+func (f *fakeRDS) DescribeDBParameterGroupsWithContext(ctx aws.Context, in *rds.DescribeDBParameterGroupsInput, opts ...request.Option) (*rds.DescribeDBParameterGroupsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.StringValue(in.DBParameterGroupName)
+	g, ok := f.parGroups[name]
+	if !ok && strings.HasPrefix(name, "default.") {
+		g, ok = &rds.DBParameterGroup{
+			DBParameterGroupName:   aws.String(name),
+			DBParameterGroupFamily: aws.String(strings.TrimPrefix(name, "default.")),
+		}, true
+	}
+	if !ok {
+		return nil, awserr.New(rds.ErrCodeDBParameterGroupNotFoundFault, fmt.Sprintf("DBParameterGroup not found: %s", name), nil)
+	}
+	return &rds.DescribeDBParameterGroupsOutput{DBParameterGroups: []*rds.DBParameterGroup{g}}, nil
+}
+
+func (f *fakeRDS) CreateDBParameterGroupWithContext(ctx aws.Context, in *rds.CreateDBParameterGroupInput, opts ...request.Option) (*rds.CreateDBParameterGroupOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.StringValue(in.DBParameterGroupName)
+	if _, ok := f.parGroups[name]; ok {
+		return nil, awserr.New(rds.ErrCodeDBParameterGroupAlreadyExistsFault, fmt.Sprintf("Parameter group %s already exists", name), nil)
+	}
+	g := &rds.DBParameterGroup{
+		DBParameterGroupName:   in.DBParameterGroupName,
+		DBParameterGroupFamily: in.DBParameterGroupFamily,
+		Description:            in.Description,
+	}
+	f.parGroups[name] = g
+	return &rds.CreateDBParameterGroupOutput{DBParameterGroup: g}, nil
+}
+
+// DescribeDBParametersPagesWithContext - only Source "user" is supported, the fake
+// doesn't know engine defaults
+func (f *fakeRDS) DescribeDBParametersPagesWithContext(ctx aws.Context, in *rds.DescribeDBParametersInput, fn func(*rds.DescribeDBParametersOutput, bool) bool, opts ...request.Option) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.StringValue(in.DBParameterGroupName)
+	if _, ok := f.parGroups[name]; !ok && !strings.HasPrefix(name, "default.") {
+		return awserr.New(rds.ErrCodeDBParameterGroupNotFoundFault, fmt.Sprintf("DBParameterGroup not found: %s", name), nil)
+	}
+	out := &rds.DescribeDBParametersOutput{}
+	for _, p := range f.parameters[name] {
+		cp := *p
+		out.Parameters = append(out.Parameters, &cp)
+	}
+	fn(out, true)
+	return nil
+}
+
+func (f *fakeRDS) ModifyDBParameterGroupWithContext(ctx aws.Context, in *rds.ModifyDBParameterGroupInput, opts ...request.Option) (*rds.DBParameterGroupNameMessage, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.StringValue(in.DBParameterGroupName)
+	if _, ok := f.parGroups[name]; !ok {
+		return nil, awserr.New(rds.ErrCodeDBParameterGroupNotFoundFault, fmt.Sprintf("DBParameterGroup not found: %s", name), nil)
+	}
+	for _, p := range in.Parameters {
+		f.setParameter(name, p)
+	}
+	f.parameterGroupChanged(name, in.Parameters)
+	return &rds.DBParameterGroupNameMessage{DBParameterGroupName: in.DBParameterGroupName}, nil
+}
+
+func (f *fakeRDS) ResetDBParameterGroupWithContext(ctx aws.Context, in *rds.ResetDBParameterGroupInput, opts ...request.Option) (*rds.DBParameterGroupNameMessage, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.StringValue(in.DBParameterGroupName)
+	if _, ok := f.parGroups[name]; !ok {
+		return nil, awserr.New(rds.ErrCodeDBParameterGroupNotFoundFault, fmt.Sprintf("DBParameterGroup not found: %s", name), nil)
+	}
+	if aws.BoolValue(in.ResetAllParameters) {
+		delete(f.parameters, name)
+	}
+	for _, p := range in.Parameters {
+		delete(f.parameters[name], aws.StringValue(p.ParameterName))
+	}
+	f.parameterGroupChanged(name, in.Parameters)
+	return &rds.DBParameterGroupNameMessage{DBParameterGroupName: in.DBParameterGroupName}, nil
+}
+
+// parameterGroupChanged - instances using `group` need a reboot if any of `params` is static
+func (f *fakeRDS) parameterGroupChanged(group string, params []*rds.Parameter) {
+	static := false
+	for _, p := range params {
+		if aws.StringValue(p.ApplyMethod) == rds.ApplyMethodPendingReboot {
+			static = true
+		}
+	}
+	if !static {
+		return
+	}
+	for _, fi := range f.instances {
+		for _, g := range fi.db.DBParameterGroups {
+			if aws.StringValue(g.DBParameterGroupName) == group {
+				g.ParameterApplyStatus = aws.String(pendingReboot)
+			}
+		}
+	}
+}
+
+func (f *fakeRDS) DescribeDBEngineVersionsWithContext(ctx aws.Context, in *rds.DescribeDBEngineVersionsInput, opts ...request.Option) (*rds.DescribeDBEngineVersionsOutput, error) {
+	engine, version := aws.StringValue(in.Engine), aws.StringValue(in.EngineVersion)
+	return &rds.DescribeDBEngineVersionsOutput{DBEngineVersions: []*rds.DBEngineVersion{{
+		Engine:                 aws.String(engine),
+		EngineVersion:          aws.String(version),
+		DBParameterGroupFamily: aws.String(fakeFamily(engine, version)),
+	}}}, nil
+}
+
+func (f *fakeRDS) DescribeOptionGroupsWithContext(ctx aws.Context, in *rds.DescribeOptionGroupsInput, opts ...request.Option) (*rds.DescribeOptionGroupsOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	name := aws.StringValue(in.OptionGroupName)
+	g, ok := f.optionGroups[name]
+	if !ok && strings.HasPrefix(name, "default:") {
+		// default:mysql-5-7
+		parts := strings.SplitN(strings.TrimPrefix(name, "default:"), "-", 2)
+		if len(parts) == 2 {
+			g, ok = &rds.OptionGroup{
+				OptionGroupName:    aws.String(name),
+				EngineName:         aws.String(parts[0]),
+				MajorEngineVersion: aws.String(strings.Replace(parts[1], "-", ".", -1)),
+			}, true
+		}
+	}
+	if !ok {
+		return nil, awserr.New(rds.ErrCodeOptionGroupNotFoundFault, fmt.Sprintf("Specified OptionGroupName: %s not found.", name), nil)
+	}
+	return &rds.DescribeOptionGroupsOutput{OptionGroupsList: []*rds.OptionGroup{g}}, nil
+}
+
+// copyDBInstance - callers must not be able to change the fake's state by
+// holding on to what Describe returned
+func copyDBInstance(db *rds.DBInstance) *rds.DBInstance {
+	c := *db
+	c.DBParameterGroups = nil
+	for _, g := range db.DBParameterGroups {
+		gc := *g
+		c.DBParameterGroups = append(c.DBParameterGroups, &gc)
+	}
+	c.ReadReplicaDBInstanceIdentifiers = append([]*string(nil), db.ReadReplicaDBInstanceIdentifiers...)
+	if db.Endpoint != nil {
+		e := *db.Endpoint
+		c.Endpoint = &e
+	}
+	return &c
+}
+
+// testWaitTimeout - the fake moves one transition per Describe, anything
+// taking longer than this is stuck
+const testWaitTimeout = time.Minute
+
+// fakeEngineVersions - what addFakeMaster creates
+var fakeEngineVersions = map[string]string{
+	"mysql":   "5.7.26",
+	"mariadb": "10.4.13",
+}
+
+// newFakeSDK - SDK backed by a fakeRDS, with the fake's endpoints resolving and
+// served by fakeMySQL
+func newFakeSDK(t *testing.T) (*SDK, *fakeRDS) {
+	resolve := lookupHost
+	lookupHost = func(host string) ([]string, error) { return []string{"10.0.0.1"}, nil }
+	t.Cleanup(func() { lookupHost = resolve })
+
+	f := newFakeRDS()
+	useFakeMySQL(t, f)
+	return &SDK{svc: f, ctx: context.Background(), log: log.New(ioutil.Discard, "", 0)}, f
+}
+
+// addFakeMaster - Multi-AZ `engine` master and it's `replicas`
+func addFakeMaster(f *fakeRDS, engine, name string, replicas ...string) {
+	f.AddInstance(&rds.DBInstance{
+		DBInstanceIdentifier: aws.String(name),
+		Engine:               aws.String(engine),
+		EngineVersion:        aws.String(fakeEngineVersions[engine]),
+		MultiAZ:              aws.Bool(true),
+	})
+	for _, replica := range replicas {
+		f.AddInstance(&rds.DBInstance{
+			DBInstanceIdentifier:                  aws.String(replica),
+			Engine:                                aws.String(engine),
+			EngineVersion:                         aws.String(fakeEngineVersions[engine]),
+			ReadReplicaSourceDBInstanceIdentifier: aws.String(name),
+		})
+		f.instances[name].db.ReadReplicaDBInstanceIdentifiers = append(f.instances[name].db.ReadReplicaDBInstanceIdentifiers, aws.String(replica))
+	}
+}
@@ -0,0 +1,155 @@
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+// option group membership statuses of a group the instance is leaving
+var leavingOptionGroup = map[string]bool{
+	"pending-removal": true,
+	"removing":        true,
+}
+
+// GroupError - an instance's parameter or option group membership we can't work with
+type GroupError struct {
+	Instance string
+	Kind     string // "DB parameter group" or "option group"
+	Reason   string
+}
+
+func (e *GroupError) Error() string {
+	return fmt.Sprintf("%s of %q: %s", e.Kind, e.Instance, e.Reason)
+}
+
+// parameterGroup - the DB parameter group of `db`, a (non Aurora) instance is only ever
+// a member of one so zero or several means RDS is telling us something we don't understand
+func parameterGroup(db *rds.DBInstance) (string, error) {
+	names := []string{}
+	for _, g := range db.DBParameterGroups {
+		names = append(names, aws.StringValue(g.DBParameterGroupName))
+	}
+	if len(names) != 1 {
+		return "", &GroupError{
+			Instance: aws.StringValue(db.DBInstanceIdentifier),
+			Kind:     "DB parameter group",
+			Reason:   fmt.Sprintf("expected exactly one, got %d: %q", len(names), strings.Join(names, commaSep)),
+		}
+	}
+	return names[0], nil
+}
+
+// optionGroup - the option group `db` is (or is becoming) a member of, nil if it has
+// none in which case RDS uses the engine default, memberships on their way out while
+// an option group change is applied are ignored
+func optionGroup(db *rds.DBInstance) (*string, error) {
+	names := []string{}
+	for _, g := range db.OptionGroupMemberships {
+		if leavingOptionGroup[aws.StringValue(g.Status)] {
+			continue
+		}
+		names = append(names, aws.StringValue(g.OptionGroupName))
+	}
+	switch len(names) {
+	case 0:
+		return nil, nil
+	case 1:
+		return aws.String(names[0]), nil
+	}
+	return nil, &GroupError{
+		Instance: aws.StringValue(db.DBInstanceIdentifier),
+		Kind:     "option group",
+		Reason:   fmt.Sprintf("member of more than one: %q", strings.Join(names, commaSep)),
+	}
+}
+
+// validateParameterGroup - `name` has to exist and be of the DB parameter group family
+// of `db`'s engine version
+func (s *SDK) validateParameterGroup(name string, db *rds.DBInstance) error {
+	out, err := s.svc.DescribeDBParameterGroupsWithContext(s.ctx, &rds.DescribeDBParameterGroupsInput{
+		DBParameterGroupName: aws.String(name),
+	})
+	if err != nil {
+		if AWSError(err, rds.ErrCodeDBParameterGroupNotFoundFault) {
+			return fmt.Errorf("DB parameter group %q does not exist", name)
+		}
+		return fmt.Errorf("ERROR: DescribeDBParameterGroupsWithContext(%s) failed with: %v", name, err)
+	}
+	if len(out.DBParameterGroups) == 0 {
+		return fmt.Errorf("DB parameter group %q does not exist", name)
+	}
+
+	family, err := s.parameterGroupFamily(db)
+	if err != nil {
+		return err
+	}
+	if got := aws.StringValue(out.DBParameterGroups[0].DBParameterGroupFamily); got != family {
+		return fmt.Errorf("DB parameter group %q is for %q, %q (%s %s) needs %q",
+			name, got, aws.StringValue(db.DBInstanceIdentifier), aws.StringValue(db.Engine), aws.StringValue(db.EngineVersion), family)
+	}
+	return nil
+}
+
+// parameterGroupFamily - the DB parameter group family of `db`'s engine version
+func (s *SDK) parameterGroupFamily(db *rds.DBInstance) (string, error) {
+	out, err := s.svc.DescribeDBEngineVersionsWithContext(s.ctx, &rds.DescribeDBEngineVersionsInput{
+		Engine:        db.Engine,
+		EngineVersion: db.EngineVersion,
+	})
+	if err != nil {
+		return "", fmt.Errorf("ERROR: DescribeDBEngineVersionsWithContext(%s %s) failed with: %v", aws.StringValue(db.Engine), aws.StringValue(db.EngineVersion), err)
+	}
+	if len(out.DBEngineVersions) == 0 {
+		return "", fmt.Errorf("unknown engine version %s %s", aws.StringValue(db.Engine), aws.StringValue(db.EngineVersion))
+	}
+	return aws.StringValue(out.DBEngineVersions[0].DBParameterGroupFamily), nil
+}
+
+// validateOptionGroup - `name` has to exist and be for `db`'s engine and major version
+func (s *SDK) validateOptionGroup(name string, db *rds.DBInstance) error {
+	out, err := s.svc.DescribeOptionGroupsWithContext(s.ctx, &rds.DescribeOptionGroupsInput{
+		OptionGroupName: aws.String(name),
+	})
+	if err != nil {
+		if AWSError(err, rds.ErrCodeOptionGroupNotFoundFault) {
+			return fmt.Errorf("option group %q does not exist", name)
+		}
+		return fmt.Errorf("ERROR: DescribeOptionGroupsWithContext(%s) failed with: %v", name, err)
+	}
+	if len(out.OptionGroupsList) == 0 {
+		return fmt.Errorf("option group %q does not exist", name)
+	}
+
+	og := out.OptionGroupsList[0]
+	engine, major := aws.StringValue(og.EngineName), aws.StringValue(og.MajorEngineVersion)
+	if engine != aws.StringValue(db.Engine) || !strings.HasPrefix(aws.StringValue(db.EngineVersion), major+".") {
+		return fmt.Errorf("option group %q is for %s %s, %q is %s %s",
+			name, engine, major, aws.StringValue(db.DBInstanceIdentifier), aws.StringValue(db.Engine), aws.StringValue(db.EngineVersion))
+	}
+	return nil
+}
+
+// sourceGroups - parameter and option group of `src` to use when restoring or creating a
+// replica from it, both validated against `src`'s engine (option group is nil if `src`
+// has none)
+func (s *SDK) sourceGroups(src Instance) (string, *string, error) {
+	parGroup, err := parameterGroup(src.RDSDBInstance)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := s.validateParameterGroup(parGroup, src.RDSDBInstance); err != nil {
+		return "", nil, err
+	}
+
+	optGroup, err := optionGroup(src.RDSDBInstance)
+	if err != nil {
+		return "", nil, err
+	}
+	if optGroup != nil {
+		if err := s.validateOptionGroup(*optGroup, src.RDSDBInstance); err != nil {
+			return "", nil, err
+		}
+	}
+	return parGroup, optGroup, nil
+}
@@ -0,0 +1,165 @@
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+// InventoryItem - an RDS instance and it's replicas (which can have replicas of their own)
+type InventoryItem struct {
+	Name             string           `json:"name"`
+	Engine           string           `json:"engine"`
+	EngineVersion    string           `json:"engine_version"`
+	InstanceClass    string           `json:"instance_class"`
+	AllocatedStorage int64            `json:"allocated_storage_gb"`
+	MultiAZ          bool             `json:"multi_az"`
+	Encrypted        bool             `json:"encrypted"`
+	KmsKeyID         string           `json:"kms_key_id,omitempty"`
+	Replicas         []*InventoryItem `json:"replicas,omitempty"`
+
+	// Unknown - not described, ie: a cross region replica (it's ARN is the Name),
+	// none of the above is known, it's listed in the inventory of it's own region
+	Unknown bool `json:"unknown,omitempty"`
+}
+
+// Inventory - every master in the region with it's replica topology
+type Inventory struct {
+	Masters []*InventoryItem `json:"masters"`
+}
+
+// Inventory - describe all instances in the region and build the master -> replica
+// tree from ReadReplicaDBInstanceIdentifiers, with `unencryptedOnly` only the trees
+// that have at least one unencrypted instance are returned (Unknown ones don't count)
+func (s *SDK) Inventory(unencryptedOnly bool) (*Inventory, error) {
+	all := make(map[string]*rds.DBInstance)
+	if err := s.svc.DescribeDBInstancesPagesWithContext(s.ctx, &rds.DescribeDBInstancesInput{},
+		func(page *rds.DescribeDBInstancesOutput, lastPage bool) bool {
+			for _, i := range page.DBInstances {
+				all[*i.DBInstanceIdentifier] = i
+			}
+			return true
+		}); err != nil {
+		return nil, fmt.Errorf("ERROR: DescribeDBInstancesPagesWithContext failed with: %v", err)
+	}
+
+	inv := &Inventory{}
+	for name, i := range all {
+		// replicas are picked up from their masters, unless the master lives
+		// in another region in which case the replica is the root of it's tree
+		if src := aws.StringValue(i.ReadReplicaSourceDBInstanceIdentifier); src != "" {
+			if _, ok := all[src]; ok {
+				continue
+			}
+		}
+
+		item := newInventoryItem(name, all)
+		if unencryptedOnly && item.allEncrypted() {
+			continue
+		}
+		inv.Masters = append(inv.Masters, item)
+	}
+
+	sort.Slice(inv.Masters, func(a, b int) bool { return inv.Masters[a].Name < inv.Masters[b].Name })
+	return inv, nil
+}
+
+func newInventoryItem(name string, all map[string]*rds.DBInstance) *InventoryItem {
+	i, ok := all[name]
+	if !ok {
+		// cross region replicas show up as ARNs we did not describe
+		return &InventoryItem{Name: name, Unknown: true}
+	}
+
+	item := &InventoryItem{
+		Name:             name,
+		Engine:           aws.StringValue(i.Engine),
+		EngineVersion:    aws.StringValue(i.EngineVersion),
+		InstanceClass:    aws.StringValue(i.DBInstanceClass),
+		AllocatedStorage: aws.Int64Value(i.AllocatedStorage),
+		MultiAZ:          aws.BoolValue(i.MultiAZ),
+		Encrypted:        aws.BoolValue(i.StorageEncrypted),
+		KmsKeyID:         aws.StringValue(i.KmsKeyId),
+	}
+	for _, replica := range i.ReadReplicaDBInstanceIdentifiers {
+		item.Replicas = append(item.Replicas, newInventoryItem(*replica, all))
+	}
+	sort.Slice(item.Replicas, func(a, b int) bool { return item.Replicas[a].Name < item.Replicas[b].Name })
+	return item
+}
+
+// allEncrypted - whether every instance of the tree we know about is encrypted
+func (item *InventoryItem) allEncrypted() bool {
+	if item.Unknown {
+		return true
+	}
+	if !item.Encrypted {
+		return false
+	}
+	for _, replica := range item.Replicas {
+		if !replica.allEncrypted() {
+			return false
+		}
+	}
+	return true
+}
+
+// Names - master names, ready to be passed to Migrate
+func (inv *Inventory) Names() []string {
+	names := []string{}
+	for _, m := range inv.Masters {
+		names = append(names, m.Name)
+	}
+	return names
+}
+
+// WriteTable - human readable inventory, replicas are indented under their master
+func (inv *Inventory) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tENGINE\tCLASS\tSIZE(GB)\tMULTI-AZ\tENCRYPTED\tKMS KEY")
+
+	var walk func(item *InventoryItem, depth int)
+	walk = func(item *InventoryItem, depth int) {
+		if item.Unknown {
+			fmt.Fprintf(tw, "%s%s\t?\t?\t?\t?\t?\t\n", strings.Repeat("  ", depth), item.Name)
+			return
+		}
+		fmt.Fprintf(tw, "%s%s\t%s %s\t%s\t%d\t%t\t%t\t%s\n",
+			strings.Repeat("  ", depth), item.Name,
+			item.Engine, item.EngineVersion,
+			item.InstanceClass,
+			item.AllocatedStorage,
+			item.MultiAZ,
+			item.Encrypted,
+			item.KmsKeyID,
+		)
+		for _, replica := range item.Replicas {
+			walk(replica, depth+1)
+		}
+	}
+	for _, m := range inv.Masters {
+		walk(m, 0)
+	}
+
+	return tw.Flush()
+}
+
+// WriteJSON - machine readable inventory, see ReadInventory
+func (inv *Inventory) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(inv)
+}
+
+// ReadInventory - read back what WriteJSON wrote, ie: to feed Names() into Migrate
+func ReadInventory(r io.Reader) (*Inventory, error) {
+	inv := &Inventory{}
+	if err := json.NewDecoder(r).Decode(inv); err != nil {
+		return nil, fmt.Errorf("ERROR: can't parse inventory: %v", err)
+	}
+	return inv, nil
+}
@@ -0,0 +1,46 @@
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+func TestInventoryCrossRegionReplica(t *testing.T) {
+	s, f := newFakeSDK(t)
+	const arn = "arn:aws:rds:eu-west-1:" + fakeAccount + ":db:prod-one-dr"
+	addFakeMaster(f, "mysql", "prod-one", "prod-one-replica")
+	addFakeMaster(f, "mysql", "prod-two")
+	for _, name := range []string{"prod-one", "prod-one-replica"} {
+		f.instances[name].db.StorageEncrypted = aws.Bool(true)
+	}
+	for _, name := range []string{"prod-one", "prod-two"} {
+		f.instances[name].db.ReadReplicaDBInstanceIdentifiers = append(f.instances[name].db.ReadReplicaDBInstanceIdentifiers, aws.String(arn))
+	}
+
+	inv, err := s.Inventory(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dr := inv.Masters[0].Replicas[0]
+	if dr.Name != arn || !dr.Unknown {
+		t.Errorf("cross region replica = %+v, want it Unknown", dr)
+	}
+	var table bytes.Buffer
+	if err := inv.WriteTable(&table); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(table.String(), arn+"  ?") {
+		t.Errorf("cross region replica isn't listed as unknown:\n%s", table.String())
+	}
+
+	// the unknown replica neither makes prod-one unencrypted nor prod-two encrypted
+	inv, err = s.Inventory(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := inv.Names(), []string{"prod-two"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Inventory(unencryptedOnly) = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,324 @@
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+const stagingSuffix = "-enc-staging"
+
+// LimitedDowntimeOptions - knobs for encrypting by promoting an encrypted replica
+type LimitedDowntimeOptions struct {
+	KmsKeyID        string
+	RootPass        string
+	ReplUser        string // replication user, created on the master if missing
+	ReplPass        string
+	BinlogRetention int // hours, has to cover the time it takes to snapshot and restore
+	NameParser      *NameParser
+	WaitTimeout     time.Duration // for replication to catch up and for each rename, 0 means defaultWaitTimeout
+}
+
+// binlogCoordinates - position in the master's binlog the encrypted copy starts replicating from
+type binlogCoordinates struct {
+	file string
+	pos  string
+}
+
+// PrepareEncryptedReplica - the "limited downtime" method: build an encrypted copy of `master`
+// and set it up as an external binlog replica of it, which can later be promoted with
+// PromoteEncryptedReplica. Since you can't create an encrypted read replica of an unencrypted
+// instance we go through a temporary unencrypted replica instead:
+//
+//  1. create a staging read replica of the master and stop replication on it
+//  2. restore it's snapshot encrypted (RestoreInstance does the attribute matching)
+//  3. point the encrypted copy at the master, at the position staging replica stopped at
+//  4. delete the staging replica
+//
+// Like RestoreInstance, attributes that could not be matched are returned as a *NotConvergedError
+// along with the (otherwise ready) encrypted copy. Masters with read replicas are refused,
+// see PromoteEncryptedReplica
+func (s *SDK) PrepareEncryptedReplica(master Instance, opts *LimitedDowntimeOptions) (Instance, error) {
+	// for now only support mysql
+	if master.Engine != "mysql" {
+		return Instance{}, fmt.Errorf("ERROR: PrepareEncryptedReplica(%s) engine %q is not supported", master.Name, master.Engine)
+	}
+	if replicas := readReplicasOf(master); len(replicas) > 0 {
+		return Instance{}, fmt.Errorf("ERROR: PrepareEncryptedReplica(%s) has read replicas %v, they can't be moved to the encrypted copy, use Migrate instead", master.Name, replicas)
+	}
+	// RDS purges binlogs as soon as it can by default, the encrypted copy would have nothing to catch up from
+	if opts.BinlogRetention <= 0 {
+		return Instance{}, fmt.Errorf("ERROR: PrepareEncryptedReplica(%s) BinlogRetention has to cover the snapshot and restore, got %d hours", master.Name, opts.BinlogRetention)
+	}
+
+	targetName := opts.NameParser.NewName(master.Name)
+	if target, err := s.Describe(targetName); err == nil {
+		if status, err := target.slaveStatus(opts.RootPass); err == nil && status != nil {
+			s.log.Printf("... PrepareEncryptedReplica: [%24s] %q is already replicating from %s", master.Name, targetName, aws.StringValue(status["Master_Host"]))
+			return target, nil
+		}
+	} else if !AWSError(err, rds.ErrCodeDBInstanceNotFoundFault) {
+		return Instance{}, err
+	}
+
+	if err := s.prepareMaster(master, opts); err != nil {
+		return Instance{}, err
+	}
+
+	stagingName := master.Name + stagingSuffix
+	if err := s.createReplica(master, master, stagingName); err != nil {
+		return Instance{}, err
+	}
+	if err := s.waitForDBStatusWithin(stagingName, opts.WaitTimeout, s.availableFunc("PrepareEncryptedReplica")); err != nil {
+		return Instance{}, err
+	}
+
+	staging, err := s.Describe(stagingName)
+	if err != nil {
+		return Instance{}, err
+	}
+	coords, err := s.stopReplication(staging, opts.RootPass)
+	if err != nil {
+		return Instance{}, err
+	}
+	s.log.Printf("... PrepareEncryptedReplica: [%24s] staging replica %q stopped at %s:%s", master.Name, stagingName, coords.file, coords.pos)
+
+	// restore with master's attributes and name, but from the staging replica's snapshot
+	src := master
+	rdsi := *master.RDSDBInstance
+	rdsi.DBInstanceIdentifier = staging.RDSDBInstance.DBInstanceIdentifier
+	src.RDSDBInstance = &rdsi
+	// attributes that couldn't be matched don't stop the replica from being set up,
+	// they're returned at the end
+	target, err := s.RestoreInstance(src, true, opts.KmsKeyID, opts.NameParser)
+	var unmatched *NotConvergedError
+	if errors.As(err, &unmatched) {
+		err = nil
+	}
+	if err != nil {
+		return Instance{}, err
+	}
+
+	if err := target.setExternalMaster(master, coords, opts); err != nil {
+		return Instance{}, err
+	}
+
+	s.log.Printf("... PrepareEncryptedReplica: [%24s] deleting staging replica %q", master.Name, stagingName)
+	if _, err := s.svc.DeleteDBInstanceWithContext(s.ctx, &rds.DeleteDBInstanceInput{
+		DBInstanceIdentifier: aws.String(stagingName),
+		SkipFinalSnapshot:    aws.Bool(true),
+	}); err != nil {
+		return Instance{}, fmt.Errorf("ERROR: DeleteDBInstanceWithContext(%s) failed with: %v", stagingName, err)
+	}
+
+	if unmatched != nil {
+		return target, unmatched
+	}
+	return target, nil
+}
+
+// ReplicationLag - Seconds_Behind_Master of `i`, -1 if replication is not running
+func (s *SDK) ReplicationLag(i Instance, rootPass string) (int, error) {
+	status, err := i.slaveStatus(rootPass)
+	if err != nil {
+		return 0, err
+	}
+	if status == nil {
+		return 0, fmt.Errorf("%q is not a replica", i.Name)
+	}
+	if status["Seconds_Behind_Master"] == nil {
+		return -1, nil
+	}
+	return strconv.Atoi(*status["Seconds_Behind_Master"])
+}
+
+// PromoteEncryptedReplica - cutover: once the writes to `master` are stopped, wait for `target`
+// to execute everything up to the master's current binlog position, detach it from the master
+// and swap the names, so `target` takes over the master's name (and endpoint) and the master
+// is left behind as old-<name>. RDS read replicas of the master would be left replicating from
+// old-<name>, so PromoteEncryptedReplica refuses to run while the master has any
+func (s *SDK) PromoteEncryptedReplica(master, target Instance, opts *LimitedDowntimeOptions) error {
+	// `master` may be from before PrepareEncryptedReplica, replicas could have been added since
+	master, err := s.Describe(master.Name)
+	if err != nil {
+		return err
+	}
+	if replicas := readReplicasOf(master); len(replicas) > 0 {
+		return fmt.Errorf("ERROR: PromoteEncryptedReplica(%s) has read replicas %v, they'd keep replicating from %s%s, delete them or use Migrate instead", master.Name, replicas, oldPrefix, master.Name)
+	}
+
+	if err := master.connect("root", opts.RootPass, "mysql"); err != nil {
+		return err
+	}
+	status, err := master.dumpQuery("show master status", map[string]interface{}{"File": nil})
+	master.DB.Close()
+	if err != nil {
+		return err
+	}
+
+	var want binlogCoordinates
+	for _, row := range status {
+		want = binlogCoordinates{file: *row["File"], pos: *row["Position"]}
+	}
+	s.log.Printf("... PromoteEncryptedReplica: [%24s] waiting for %q to catch up with %s:%s", master.Name, target.Name, want.file, want.pos)
+
+	readyFunc := func(i Instance) bool {
+		status, err := i.slaveStatus(opts.RootPass)
+		if err != nil || status == nil {
+			s.log.Printf("... PromoteEncryptedReplica: [%24s] can't get slave status: %v", i.Name, err)
+			return false
+		}
+		got := binlogCoordinates{
+			file: aws.StringValue(status["Relay_Master_Log_File"]),
+			pos:  aws.StringValue(status["Exec_Master_Log_Pos"]),
+		}
+		ok := got == want
+		if !ok {
+			s.log.Printf("... PromoteEncryptedReplica: [%24s] waiting for replication to catch up, want: %s:%s got: %s:%s lag: %ss",
+				i.Name, want.file, want.pos, got.file, got.pos, aws.StringValue(status["Seconds_Behind_Master"]))
+		}
+		return ok
+	}
+	if err := s.waitForDBStatusWithin(target.Name, opts.WaitTimeout, readyFunc); err != nil {
+		return err
+	}
+
+	s.log.Printf("... PromoteEncryptedReplica: [%24s] promoting %q", master.Name, target.Name)
+	if err := target.connect("root", opts.RootPass, "mysql"); err != nil {
+		return err
+	}
+	defer target.DB.Close()
+
+	for _, cmd := range []string{
+		"call mysql.rds_stop_replication",
+		"call mysql.rds_reset_external_master",
+	} {
+		s.log.Printf("... PromoteEncryptedReplica: [%24s] cmd: %q", target.Name, cmd)
+		if _, err := target.DB.Exec(cmd); err != nil {
+			return fmt.Errorf("ERROR: %q on %q failed with: %v", cmd, target.Name, err)
+		}
+	}
+
+	// same as SwapInstances, the master's name has to be free before target can take it
+	s.log.Printf("... PromoteEncryptedReplica: [%24s] renaming to %s%s", master.Name, oldPrefix, master.Name)
+	if err := s.renameAll([]renamePair{{from: master.Name, to: oldPrefix + master.Name}}, opts.WaitTimeout); err != nil {
+		return err
+	}
+
+	s.log.Printf("... PromoteEncryptedReplica: [%24s] renaming %q to %q", master.Name, target.Name, master.Name)
+	return s.renameAll([]renamePair{{from: target.Name, to: master.Name}}, opts.WaitTimeout)
+}
+
+// prepareMaster - make sure the master keeps it's binlogs long enough and
+// has a user the encrypted copy can replicate with
+func (s *SDK) prepareMaster(master Instance, opts *LimitedDowntimeOptions) error {
+	if err := master.connect("root", opts.RootPass, "mysql"); err != nil {
+		return err
+	}
+	defer master.DB.Close()
+
+	rc := &mysqlReplicaClone{
+		copyTo:       master,
+		binlogRetHrs: aws.Int(opts.BinlogRetention),
+		log:          s.log,
+	}
+	dryRun := false
+	if err := rc.setBinlogRetention(s.Verbose, dryRun); err != nil {
+		return err
+	}
+
+	// placeholders, so neither the user nor the password end up in the log or need escaping
+	s.log.Printf("... PrepareEncryptedReplica: [%24s] creating replication user %q", master.Name, opts.ReplUser)
+	if _, err := master.DB.Exec("CREATE USER IF NOT EXISTS ?@'%' IDENTIFIED BY ?", opts.ReplUser, opts.ReplPass); err != nil {
+		return fmt.Errorf("ERROR: can't create replication user %q on %q: %v", opts.ReplUser, master.Name, err)
+	}
+	s.log.Printf("... PrepareEncryptedReplica: [%24s] granting replication privs to %q", master.Name, opts.ReplUser)
+	if _, err := master.DB.Exec("GRANT REPLICATION SLAVE, REPLICATION CLIENT ON *.* TO ?@'%'", opts.ReplUser); err != nil {
+		return fmt.Errorf("ERROR: can't grant replication privs to %q on %q: %v", opts.ReplUser, master.Name, err)
+	}
+	return nil
+}
+
+// slaveStatus - `show slave status` row, nil if `i` is not a replica
+func (i *Instance) slaveStatus(rootPass string) (map[string]*string, error) {
+	if err := i.connect("root", rootPass, "mysql"); err != nil {
+		return nil, err
+	}
+	defer i.DB.Close()
+
+	rows, err := i.dumpQuery("show slave status", map[string]interface{}{"Master_Host": nil})
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		return row, nil
+	}
+	return nil, nil
+}
+
+// readReplicasOf - RDS read replicas of `master`, except for the staging replica of
+// PrepareEncryptedReplica (which lingers in the list while it's being deleted)
+func readReplicasOf(master Instance) []string {
+	replicas := []string{}
+	for _, id := range master.RDSDBInstance.ReadReplicaDBInstanceIdentifiers {
+		if name := aws.StringValue(id); name != master.Name+stagingSuffix {
+			replicas = append(replicas, name)
+		}
+	}
+	return replicas
+}
+
+// stopReplication - stop replication on `i` and return the master's binlog position it stopped at
+func (s *SDK) stopReplication(i Instance, rootPass string) (binlogCoordinates, error) {
+	if err := i.connect("root", rootPass, "mysql"); err != nil {
+		return binlogCoordinates{}, err
+	}
+	if _, err := i.DB.Exec("call mysql.rds_stop_replication"); err != nil {
+		i.DB.Close()
+		return binlogCoordinates{}, fmt.Errorf("ERROR: can't stop replication on %q: %v", i.Name, err)
+	}
+	i.DB.Close()
+
+	// give SQL thread a moment to finish applying what it already has
+	if err := s.pause(defaultSleep); err != nil {
+		return binlogCoordinates{}, err
+	}
+
+	status, err := i.slaveStatus(rootPass)
+	if err != nil {
+		return binlogCoordinates{}, err
+	}
+	if status == nil {
+		return binlogCoordinates{}, fmt.Errorf("%q is not a replica", i.Name)
+	}
+	return binlogCoordinates{
+		file: *status["Relay_Master_Log_File"],
+		pos:  *status["Exec_Master_Log_Pos"],
+	}, nil
+}
+
+// setExternalMaster - start replicating from `master` at `coords`
+func (i *Instance) setExternalMaster(master Instance, coords binlogCoordinates, opts *LimitedDowntimeOptions) error {
+	if err := i.connect("root", opts.RootPass, "mysql"); err != nil {
+		return err
+	}
+	defer i.DB.Close()
+
+	if _, err := i.DB.Exec("call mysql.rds_set_external_master(?, ?, ?, ?, ?, ?, 0)",
+		*master.RDSDBInstance.Endpoint.Address,
+		*master.RDSDBInstance.Endpoint.Port,
+		opts.ReplUser,
+		opts.ReplPass,
+		coords.file,
+		coords.pos,
+	); err != nil {
+		return fmt.Errorf("ERROR: rds_set_external_master on %q failed with: %v", i.Name, err)
+	}
+
+	if _, err := i.DB.Exec("call mysql.rds_start_replication"); err != nil {
+		return fmt.Errorf("ERROR: rds_start_replication on %q failed with: %v", i.Name, err)
+	}
+	return nil
+}
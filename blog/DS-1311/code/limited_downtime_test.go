@@ -0,0 +1,45 @@
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+func TestEncryptedReplicaRefusesReadReplicas(t *testing.T) {
+	s, f := newFakeSDK(t)
+	addFakeMaster(f, "mysql", "prod-one", "prod-one-replica")
+	f.AddInstance(&rds.DBInstance{
+		DBInstanceIdentifier: aws.String("new-prod-one"),
+		Engine:               aws.String("mysql"),
+		EngineVersion:        aws.String(fakeEngineVersions["mysql"]),
+		StorageEncrypted:     aws.Bool(true),
+	})
+	master, err := s.Describe("prod-one")
+	if err != nil {
+		t.Fatal(err)
+	}
+	target, err := s.Describe("new-prod-one")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := &LimitedDowntimeOptions{BinlogRetention: 24, NameParser: &NameParser{}, WaitTimeout: testWaitTimeout}
+
+	if _, err := s.PrepareEncryptedReplica(master, opts); err == nil || !strings.Contains(err.Error(), "has read replicas [prod-one-replica]") {
+		t.Errorf("PrepareEncryptedReplica() = %v, want it to refuse the read replica", err)
+	}
+	if _, ok := f.instances["prod-one"+stagingSuffix]; ok {
+		t.Error("PrepareEncryptedReplica() created the staging replica")
+	}
+
+	// described before the replica was added
+	stale := *master.RDSDBInstance
+	stale.ReadReplicaDBInstanceIdentifiers = nil
+	master.RDSDBInstance = &stale
+	if err := s.PromoteEncryptedReplica(master, target, opts); err == nil || !strings.Contains(err.Error(), "has read replicas [prod-one-replica]") {
+		t.Errorf("PromoteEncryptedReplica() = %v, want it to refuse the read replica", err)
+	}
+	if _, err := s.Describe("prod-one"); err != nil {
+		t.Errorf("PromoteEncryptedReplica() renamed the master: %v", err)
+	}
+}
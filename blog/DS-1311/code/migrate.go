@@ -0,0 +1,425 @@
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+const oldPrefix = "old-"
+
+// MigrateOptions - knobs for the encryption migration, mirrors the
+// parameters RestoreInstance and reCreateReplica already take
+type MigrateOptions struct {
+	KmsKeyID        string
+	TakeFreshSnap   bool
+	RootPass        string
+	BinlogRetention int // hours, 0 copies it from the instance being replaced
+	NameParser      *NameParser
+	Drain           DrainOptions
+	Concurrency     int    // how many masters (and replicas of a master) to work on at once
+	RunID           string // if set, snapshots are managed (named, tagged) by SnapshotManager
+	StateFile       string // resume from / journal into this file, in memory only if empty
+	Users           UserCloneOptions
+	WaitTimeout     time.Duration // for each reboot, rename and snapshot, 0 means defaultWaitTimeout
+
+	// CloneParameterGroups - give the encrypted instances their own clones (named by NameParser)
+	// of the parameter groups instead of sharing them with the instances being replaced
+	CloneParameterGroups bool
+}
+
+// migration - state of a single master (and it's replicas) going through
+// the cold backup / restore runbook
+type migration struct {
+	s       *SDK
+	opts    *MigrateOptions
+	name    string // original master name, ie: prod-one
+	journal *MigrationState
+	state   *InstanceState
+}
+
+type migrationStep struct {
+	name string
+	run  func(m *migration) error
+}
+
+// migrationSteps - runbook steps 4 through 11, in the order they have to be executed
+var migrationSteps = []migrationStep{
+	{"rename to " + oldPrefix, (*migration).renameToOld},
+	{"reboot", (*migration).reboot},
+	{"verify no connections", (*migration).verifyNoConnections},
+	{"snapshot and encrypt", (*migration).snapshot},
+	{"restore", (*migration).restore},
+	{"recreate replicas", (*migration).reCreateReplicas},
+	{"clone users", (*migration).cloneUsers},
+	{"validate", (*migration).validate},
+	{"rename back", (*migration).renameBack},
+}
+
+// Migrate - drive every RDS instance in `names` (masters only, replicas are
+// discovered) through the entire cold backup / restore encryption runbook:
+// rename to old-, reboot, verify no connections, snapshot, encrypt, restore,
+// recreate replicas, clone users, validate and rename back to the original name ...
+// every completed step is journaled to opts.StateFile, re-running with the
+// same file skips whatever has already been done. Up to opts.Concurrency masters
+// are migrated in parallel, a failure of one doesn't stop the others and all of
+// the failures are returned together as a MultiError
+func (s *SDK) Migrate(names []string, opts *MigrateOptions) error {
+	journal, err := LoadMigrationState(opts.StateFile)
+	if err != nil {
+		return err
+	}
+
+	return s.forEach(names, opts.Concurrency, func(name string) error {
+		m := &migration{s: s, opts: opts, name: name, journal: journal, state: journal.Instance(name)}
+		return m.run()
+	})
+}
+
+func (m *migration) run() error {
+	if m.state.RolledBack {
+		return fmt.Errorf("ERROR: Migrate(%s) was rolled back, remove it from %q to start over", m.name, m.opts.StateFile)
+	}
+
+	for n, step := range migrationSteps {
+		if m.state.Done(step.name) {
+			m.s.log.Printf("... Migrate: [%24s] step %d/%d: %s (already done)", m.name, n+1, len(migrationSteps), step.name)
+			continue
+		}
+
+		m.s.log.Printf("... Migrate: [%24s] step %d/%d: %s", m.name, n+1, len(migrationSteps), step.name)
+		if err := step.run(m); err != nil {
+			return fmt.Errorf("ERROR: Migrate(%s) step %q failed with: %v", m.name, step.name, err)
+		}
+
+		if err := m.update(func(is *InstanceState) {
+			is.CompletedSteps = append(is.CompletedSteps, step.name)
+		}); err != nil {
+			return err
+		}
+	}
+	m.s.log.Printf("... Migrate: [%24s] done", m.name)
+	return nil
+}
+
+// update - record progress in the journal
+func (m *migration) update(f func(is *InstanceState)) error {
+	return m.journal.Update(m.name, f)
+}
+
+func (m *migration) oldName(name string) string {
+	return oldPrefix + name
+}
+
+// all - names of the master and all of it's replicas
+func (m *migration) all() []string {
+	return append([]string{m.name}, m.state.Replicas...)
+}
+
+// newName - name of the encrypted instance replacing `name`, ie: new-old-prod-one
+func (m *migration) newName(name string) string {
+	return m.opts.NameParser.NewName(m.oldName(name))
+}
+
+func (m *migration) renameToOld() error {
+	// discover replicas before renaming, unless we are re-running this
+	// step and the master has already been renamed
+	i, err := m.s.Describe(m.name)
+	if err != nil {
+		if !AWSError(err, rds.ErrCodeDBInstanceNotFoundFault) {
+			return err
+		}
+		if i, err = m.s.Describe(m.oldName(m.name)); err != nil {
+			return err
+		}
+	}
+
+	replicas := []string{}
+	for _, replica := range i.RDSDBInstance.ReadReplicaDBInstanceIdentifiers {
+		replicas = append(replicas, strings.TrimPrefix(*replica, oldPrefix))
+	}
+	if err := m.update(func(is *InstanceState) { is.Replicas = replicas }); err != nil {
+		return err
+	}
+
+	// remember where everything was, Rollback verifies against it
+	endpoints := make(map[string]string)
+	for _, name := range m.all() {
+		if e, ok := m.state.Endpoints[name]; ok {
+			endpoints[name] = e
+			continue
+		}
+		i, err := m.s.Describe(name)
+		if err != nil {
+			return err
+		}
+		endpoints[name] = endpointAddress(i)
+	}
+	if err := m.update(func(is *InstanceState) { is.Endpoints = endpoints }); err != nil {
+		return err
+	}
+
+	pairs := []renamePair{}
+	for _, name := range m.all() {
+		pairs = append(pairs, renamePair{from: name, to: m.oldName(name)})
+	}
+	return m.s.renameAll(pairs, m.opts.WaitTimeout)
+}
+
+func (m *migration) reboot() error {
+	since := time.Now()
+	for _, name := range m.all() {
+		old := m.oldName(name)
+		m.s.log.Printf("... Migrate: [%24s] rebooting %q to clear any connections", m.name, old)
+		if err := m.s.Reboot(old, false); err != nil {
+			return err
+		}
+	}
+
+	timeline := &EventTimeline{}
+	for _, name := range m.all() {
+		if err := m.s.waitForDBEvent(m.oldName(name), since, m.opts.WaitTimeout, []string{eventRestarted}, m.s.availableFunc("Migrate"), timeline); err != nil {
+			return err
+		}
+	}
+	return m.update(func(is *InstanceState) {
+		is.Events = append(is.Events, timeline.Events()...)
+	})
+}
+
+func (m *migration) verifyNoConnections() error {
+	for _, name := range m.all() {
+		i, err := m.s.Describe(m.oldName(name))
+		if err != nil {
+			return err
+		}
+		if err := m.s.DrainConnections(i, m.opts.RootPass, m.opts.Drain); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *migration) snapshot() error {
+	source, err := m.s.Describe(m.oldName(m.name))
+	if err != nil {
+		return err
+	}
+
+	// somebody could have sneaked in since the previous step
+	var snap *rds.DBSnapshot
+	if m.opts.RunID != "" {
+		if err = m.s.DrainConnections(source, m.opts.RootPass, m.opts.Drain); err == nil {
+			sm := m.s.NewSnapshotManager(m.opts.RunID)
+			sm.WaitTimeout = m.opts.WaitTimeout
+			snap, err = sm.CreateEncrypted(source.Name, m.opts.KmsKeyID)
+		}
+	} else {
+		snap, err = m.s.QuiescedSnapshot(source, m.opts.RootPass, m.opts.Drain, m.opts.TakeFreshSnap, m.opts.KmsKeyID)
+	}
+	if err != nil {
+		return err
+	}
+	m.s.log.Printf("... Migrate: [%24s] encrypted snapshot %q is ready", m.name, *snap.DBSnapshotIdentifier)
+
+	return m.update(func(is *InstanceState) {
+		is.SnapshotID = aws.StringValue(snap.SourceDBSnapshotIdentifier)
+		is.EncryptedSnapshotID = aws.StringValue(snap.DBSnapshotIdentifier)
+	})
+}
+
+func (m *migration) restore() error {
+	source, err := m.s.Describe(m.oldName(m.name))
+	if err != nil {
+		return err
+	}
+
+	// always the journaled snapshot, ie: on a resumed run GenerateSnapshot
+	// could pick up a newer one than the snapshot step took
+	snap, err := m.s.NewSnapshotManager(m.opts.RunID).describe(m.state.EncryptedSnapshotID)
+	if err != nil {
+		return err
+	}
+	if snap == nil {
+		return fmt.Errorf("encrypted snapshot %q not found", m.state.EncryptedSnapshotID)
+	}
+	var i Instance
+	timeline := &EventTimeline{}
+	if m.opts.CloneParameterGroups {
+		i, err = m.restoreCloningParameterGroup(source, snap, timeline)
+	} else {
+		i, err = m.s.restoreInstanceFromSnapshot(source, snap, m.opts.NameParser, timeline)
+	}
+
+	// unmatched attributes are journaled for the validate step to refuse
+	var unmatched []string
+	var nc *NotConvergedError
+	if errors.As(err, &nc) {
+		unmatched, err = nc.Fields, nil
+	}
+	if err != nil {
+		return err
+	}
+
+	// RestoreInstance returns only after ModifyInstance is done
+	return m.update(func(is *InstanceState) {
+		is.RestoredName = i.Name
+		is.ModifyDone = true
+		is.Unmatched = unmatched
+		is.Events = append(is.Events, timeline.Events()...)
+	})
+}
+
+// restoreCloningParameterGroup - restore `snap` with a clone of `source`'s parameter group
+func (m *migration) restoreCloningParameterGroup(source Instance, snap *rds.DBSnapshot, timeline *EventTimeline) (Instance, error) {
+	group, err := parameterGroup(source.RDSDBInstance)
+	if err != nil {
+		return Instance{}, err
+	}
+	return m.s.RestoreInstanceElsewhere(m.s, source, snap, m.opts.NameParser, &RestoreOverrides{
+		DBParameterGroupName: clonedGroupName(group, m.opts.NameParser),
+		CloneParameterGroup:  true,
+		AvailabilityZone:     aws.StringValue(source.RDSDBInstance.AvailabilityZone),
+		Timeline:             timeline,
+	})
+}
+
+// pending - replicas not yet in `done`, checked up front since `done` is
+// appended to by the replicas being worked on in parallel
+func (m *migration) pending(done []string) []string {
+	names := []string{}
+	for _, name := range m.state.Replicas {
+		if !contains(done, m.newName(name)) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// reCreateReplicas - the master is already restored at this point, so
+// all of it's replicas can be created in parallel
+func (m *migration) reCreateReplicas() error {
+	return m.s.forEach(m.pending(m.state.ReplicasCreated), m.opts.Concurrency, func(name string) error {
+		newName := m.newName(name)
+		copyFrom, err := m.s.Describe(m.oldName(name))
+		if err != nil {
+			return err
+		}
+
+		// always describe the master, createReplica relies on it's ReadReplicaDBInstanceIdentifiers
+		master, err := m.s.Describe(m.newName(m.name))
+		if err != nil {
+			return err
+		}
+
+		if err := m.s.createReplica(master, copyFrom, newName); err != nil {
+			return err
+		}
+		groupName, err := parameterGroup(copyFrom.RDSDBInstance)
+		if err != nil {
+			return err
+		}
+		// ModifyInstance (or ModifyInstanceCloningParameterGroup), keeping the events
+		want := &DesiredState{
+			DBParameterGroupName: groupName,
+			VpcSecurityGroupIds:  copyFrom.FilterVPCSecurityGroups(Active),
+			Timeline:             &EventTimeline{},
+		}
+		if m.opts.CloneParameterGroups {
+			want.DBParameterGroupName = clonedGroupName(groupName, m.opts.NameParser)
+			want.CloneParameterGroupFrom = &ParameterGroupRef{Name: groupName}
+		}
+		if _, err := m.s.Reconcile(newName, want); err != nil {
+			return err
+		}
+
+		return m.update(func(is *InstanceState) {
+			is.ReplicasCreated = append(is.ReplicasCreated, newName)
+			is.Events = append(is.Events, want.Timeline.Events()...)
+		})
+	})
+}
+
+func (m *migration) cloneUsers() error {
+	master, err := m.s.Describe(m.newName(m.name))
+	if err != nil {
+		return err
+	}
+
+	return m.s.forEach(m.pending(m.state.UsersCloned), m.opts.Concurrency, func(name string) error {
+		newName := m.newName(name)
+		copyFrom, err := m.s.Describe(m.oldName(name))
+		if err != nil {
+			return err
+		}
+		newReplica, err := m.s.Describe(newName)
+		if err != nil {
+			return err
+		}
+
+		if err := m.s.cloneReplica(master, copyFrom, newReplica, m.opts.BinlogRetention, m.opts.RootPass, m.opts.Users); err != nil {
+			return err
+		}
+
+		return m.update(func(is *InstanceState) {
+			is.UsersCloned = append(is.UsersCloned, newName)
+		})
+	})
+}
+
+func (m *migration) validate() error {
+	if len(m.state.Unmatched) > 0 {
+		return fmt.Errorf("%q could not match %s with %q", m.newName(m.name), strings.Join(m.state.Unmatched, commaSep), m.name)
+	}
+	for _, name := range m.all() {
+		newName := m.newName(name)
+		i, err := m.s.Describe(newName)
+		if err != nil {
+			return err
+		}
+		if i.Status != Available {
+			return fmt.Errorf("%q has status %q, want %q", newName, i.Status, Available)
+		}
+		if !aws.BoolValue(i.RDSDBInstance.StorageEncrypted) {
+			return fmt.Errorf("%q is not encrypted", newName)
+		}
+		m.s.log.Printf("... Migrate: [%24s] %q is encrypted with %q", m.name, newName, aws.StringValue(i.RDSDBInstance.KmsKeyId))
+
+		old, err := m.s.Describe(m.oldName(name))
+		if err != nil {
+			return err
+		}
+		diffs, err := compareInstances(old, i, m.expectedDiffs())
+		m.s.logDiffs(old, i, diffs)
+		if err != nil {
+			return err
+		}
+		if len(old.RDSDBInstance.ReadReplicaDBInstanceIdentifiers) != len(i.RDSDBInstance.ReadReplicaDBInstanceIdentifiers) {
+			return fmt.Errorf("%q has %d replica(s), %q has %d", old.Name, len(old.RDSDBInstance.ReadReplicaDBInstanceIdentifiers),
+				newName, len(i.RDSDBInstance.ReadReplicaDBInstanceIdentifiers))
+		}
+	}
+	return nil
+}
+
+// expectedDiffs - expectedDiffs, plus the parameter groups if they were cloned
+func (m *migration) expectedDiffs() map[string]bool {
+	if !m.opts.CloneParameterGroups {
+		return expectedDiffs
+	}
+	expected := map[string]bool{"DBParameterGroups": true}
+	for field := range expectedDiffs {
+		expected[field] = true
+	}
+	return expected
+}
+
+func (m *migration) renameBack() error {
+	pairs := []renamePair{}
+	for _, name := range m.all() {
+		pairs = append(pairs, renamePair{from: m.newName(name), to: name})
+	}
+	return m.s.renameAll(pairs, m.opts.WaitTimeout)
+}
@@ -0,0 +1,201 @@
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+// testMigrateOptions - managed snapshots and a journal in a temp dir
+func testMigrateOptions(t *testing.T) *MigrateOptions {
+	return &MigrateOptions{
+		KmsKeyID:        "arn:aws:kms:" + fakeRegion + ":" + fakeAccount + ":key/test",
+		NameParser:      &NameParser{},
+		Concurrency:     1,
+		RunID:           "test",
+		StateFile:       filepath.Join(t.TempDir(), "state.json"),
+		WaitTimeout:     testWaitTimeout,
+		BinlogRetention: 24,
+	}
+}
+
+// seedAccounts - an application user with a schema grant
+func seedAccounts(m *fakeMySQL) {
+	m.AddRow("mysql.user", map[string]string{
+		"Host":                  "%",
+		"User":                  "app",
+		"authentication_string": nativeHash,
+		"plugin":                nativePassword,
+		"Select_priv":           "Y",
+		"Insert_priv":           "N",
+		"Grant_priv":            "N",
+	})
+	m.AddRow("mysql.db", map[string]string{"Host": "%", "Db": "app", "User": "app", "Select_priv": "Y", "Insert_priv": "Y"})
+}
+
+// lingeringSession - an idle application connection
+var lingeringSession = map[string]string{"ID": "42", "USER": "app", "HOST": "10.0.0.2:50000", "DB": "app", "COMMAND": "Sleep", "TIME": "3"}
+
+func TestMigrateAndRollback(t *testing.T) {
+	s, f := newFakeSDK(t)
+	addFakeMaster(f, "mysql", "prod-one", "prod-one-replica")
+	seedAccounts(f.MySQL("prod-one-replica"))
+	// a custom parameter group, for the restored instances to be rebooted into
+	f.AddParameterGroup("prod-params", fakeFamily("mysql", fakeEngineVersions["mysql"]))
+	for _, name := range []string{"prod-one", "prod-one-replica"} {
+		f.instances[name].db.DBParameterGroups = []*rds.DBParameterGroupStatus{{DBParameterGroupName: aws.String("prod-params"), ParameterApplyStatus: aws.String(inSync)}}
+	}
+	opts := testMigrateOptions(t)
+
+	if err := s.Migrate([]string{"prod-one"}, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	// the encrypted replica is created from the encrypted master, not restored from the
+	// old replica's snapshot, so it's users have to be cloned from the old replica
+	execs := f.MySQL("prod-one-replica").Execs()
+	for _, want := range []string{
+		"CREATE USER 'app'@'%' IDENTIFIED WITH mysql_native_password AS '" + nativeHash + "'",
+		"GRANT SELECT ON *.* TO 'app'@'%'",
+		"GRANT INSERT, SELECT ON `app`.* TO 'app'@'%'",
+		"call mysql.rds_set_configuration('binlog retention hours', '24')",
+	} {
+		if !contains(execs, want) {
+			t.Errorf("%q wasn't executed on the encrypted replica, got:\n%s", want, strings.Join(execs, "\n"))
+		}
+	}
+	for _, name := range []string{"prod-one", "prod-one-replica"} {
+		i, err := s.Describe(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !aws.BoolValue(i.RDSDBInstance.StorageEncrypted) {
+			t.Errorf("after Migrate %q is not encrypted", name)
+		}
+		if _, err := s.Describe(oldPrefix + name); err != nil {
+			t.Errorf("after Migrate %q is gone: %v", oldPrefix+name, err)
+		}
+	}
+
+	journal, err := LoadMigrationState(opts.StateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	is := journal.Instance("prod-one")
+	if len(is.CompletedSteps) != len(migrationSteps) {
+		t.Errorf("journaled steps %v, want all %d", is.CompletedSteps, len(migrationSteps))
+	}
+	// the reboots applying the parameter group to the restored master and new replica
+	rebooted := map[string]bool{}
+	for _, e := range is.Events {
+		if e.Message == "DB instance restarted" {
+			rebooted[e.Source] = true
+		}
+	}
+	for _, name := range []string{opts.NameParser.NewName(oldPrefix + "prod-one"), opts.NameParser.NewName(oldPrefix + "prod-one-replica")} {
+		if !rebooted[name] {
+			t.Errorf("reboot of %q wasn't journaled, got: %v", name, is.Events)
+		}
+	}
+
+	if err := s.Rollback(nil, opts); err != nil {
+		t.Fatal(err)
+	}
+	master, err := s.Describe("prod-one")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if aws.BoolValue(master.RDSDBInstance.StorageEncrypted) {
+		t.Error("after Rollback prod-one is still the encrypted instance")
+	}
+	if _, err := s.Describe(opts.NameParser.NewName(oldPrefix + "prod-one")); err != nil {
+		t.Errorf("after Rollback the encrypted instance is gone: %v", err)
+	}
+
+	if err := s.Migrate([]string{"prod-one"}, opts); err == nil {
+		t.Error("Migrate() of a rolled back instance succeeded")
+	}
+}
+
+func TestRollbackPartialRename(t *testing.T) {
+	s, f := newFakeSDK(t)
+	addFakeMaster(f, "mysql", "prod-one", "prod-one-replica")
+	opts := &MigrateOptions{
+		NameParser: &NameParser{},
+		StateFile:  filepath.Join(t.TempDir(), "state.json"),
+	}
+
+	// rename to old- was journaled as started but not as done, and only the master made it
+	journal, err := LoadMigrationState(opts.StateFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := journal.Update("prod-one", func(is *InstanceState) {
+		is.Replicas = []string{"prod-one-replica"}
+		is.Endpoints = map[string]string{"prod-one": "prod-one" + fakeDNSSuffix, "prod-one-replica": "prod-one-replica" + fakeDNSSuffix}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.renameAll([]renamePair{{from: "prod-one", to: oldPrefix + "prod-one"}}, testWaitTimeout); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Rollback(nil, opts); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Describe(oldPrefix + "prod-one"); !AWSError(err, rds.ErrCodeDBInstanceNotFoundFault) {
+		t.Errorf("Describe(%s) = %v, want %s", oldPrefix+"prod-one", err, rds.ErrCodeDBInstanceNotFoundFault)
+	}
+	replica, err := s.Describe("prod-one-replica")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if source := aws.StringValue(replica.RDSDBInstance.ReadReplicaSourceDBInstanceIdentifier); source != "prod-one" {
+		t.Errorf("prod-one-replica replicates from %q, want prod-one", source)
+	}
+}
+
+func TestMigrateNonMySQL(t *testing.T) {
+	s, f := newFakeSDK(t)
+	addFakeMaster(f, "mariadb", "prod-one", "prod-one-replica")
+
+	// connection checks and user cloning are mysql only
+	if err := s.Migrate([]string{"prod-one"}, testMigrateOptions(t)); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"prod-one", "prod-one-replica", oldPrefix + "prod-one", oldPrefix + "prod-one-replica"} {
+		if f.instances[name].mysql != nil {
+			t.Errorf("connected to %q", name)
+		}
+	}
+}
+
+func TestMigrateKillsLingeringConnections(t *testing.T) {
+	s, f := newFakeSDK(t)
+	addFakeMaster(f, "mysql", "prod-one")
+	f.MySQL("prod-one").AddRow("information_schema.processlist", lingeringSession)
+	opts := testMigrateOptions(t)
+	opts.Drain = DrainOptions{Kill: true, Deadline: testWaitTimeout}
+
+	if err := s.Migrate([]string{"prod-one"}, opts); err != nil {
+		t.Fatal(err)
+	}
+	if execs := f.MySQL(oldPrefix + "prod-one").Execs(); !contains(execs, "call mysql.rds_kill('42')") {
+		t.Errorf("lingering session wasn't killed, got: %q", execs)
+	}
+}
+
+func TestMigrateRefusesLingeringConnections(t *testing.T) {
+	s, f := newFakeSDK(t)
+	addFakeMaster(f, "mysql", "prod-one")
+	f.MySQL("prod-one").AddRow("information_schema.processlist", lingeringSession)
+
+	err := s.Migrate([]string{"prod-one"}, testMigrateOptions(t))
+	if err == nil || !strings.Contains(err.Error(), "still has 1 connection(s): app@10.0.0.2:50000") {
+		t.Fatalf("Migrate() = %v, want it to refuse the lingering connection", err)
+	}
+	if len(f.snapshots) > 0 {
+		t.Errorf("snapshot taken with connections open: %d snapshot(s)", len(f.snapshots))
+	}
+}
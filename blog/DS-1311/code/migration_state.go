@@ -0,0 +1,166 @@
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// MigrationState - on-disk (JSON) journal of a migration run, saved after
+// every completed step so a crashed or interrupted run resumes exactly where
+// it stopped instead of re-inferring state from Describe ...
+type MigrationState struct {
+	Instances map[string]*InstanceState `json:"instances"`
+
+	path string
+	mu   sync.Mutex
+}
+
+// InstanceState - what has been done so far for a single master and it's replicas
+type InstanceState struct {
+	Name                string            `json:"name"`
+	Replicas            []string          `json:"replicas,omitempty"`
+	CompletedSteps      []string          `json:"completed_steps,omitempty"`
+	SnapshotID          string            `json:"snapshot_id,omitempty"`
+	EncryptedSnapshotID string            `json:"encrypted_snapshot_id,omitempty"`
+	RestoredName        string            `json:"restored_name,omitempty"`
+	ModifyDone          bool              `json:"modify_done,omitempty"`
+	Unmatched           []string          `json:"unmatched,omitempty"` // attributes the restore could not match, fail validation
+	ReplicasCreated     []string          `json:"replicas_created,omitempty"`
+	UsersCloned         []string          `json:"users_cloned,omitempty"`
+	Endpoints           map[string]string `json:"endpoints,omitempty"` // pre-migration endpoint addresses
+	RolledBack          bool              `json:"rolled_back,omitempty"`
+	Events              []EventRecord     `json:"events,omitempty"`
+}
+
+// LoadMigrationState - read the journal from `path`, a missing file is
+// a fresh run, and an empty `path` keeps the journal in memory only
+func LoadMigrationState(path string) (*MigrationState, error) {
+	st := &MigrationState{
+		Instances: make(map[string]*InstanceState),
+		path:      path,
+	}
+	if path == "" {
+		return st, nil
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return st, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(b, st); err != nil {
+		return nil, fmt.Errorf("ERROR: can't parse migration state %q: %v", path, err)
+	}
+	if st.Instances == nil {
+		st.Instances = make(map[string]*InstanceState)
+	}
+	return st, nil
+}
+
+// Instance - journal entry for `name`, created if missing
+func (st *MigrationState) Instance(name string) *InstanceState {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	is, ok := st.Instances[name]
+	if !ok {
+		is = &InstanceState{Name: name}
+		st.Instances[name] = is
+	}
+	return is
+}
+
+// Update - apply `f` to the `name` entry and persist the journal
+func (st *MigrationState) Update(name string, f func(is *InstanceState)) error {
+	is := st.Instance(name)
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	f(is)
+	return st.save()
+}
+
+// save - write to a temp file and rename it over the journal so we never
+// leave a half written file behind if we get killed mid-write
+func (st *MigrationState) save() error {
+	if st.path == "" {
+		return nil
+	}
+
+	b, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(st.path), filepath.Base(st.path)+".")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), st.path)
+}
+
+// WriteReport - human readable summary of the run: steps completed
+// and the RDS event timeline of every instance
+func (st *MigrationState) WriteReport(w io.Writer) error {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	names := []string{}
+	for name := range st.Instances {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		is := st.Instances[name]
+		lines := []string{
+			fmt.Sprintf("### %s", name),
+			fmt.Sprintf("replicas:        %v", is.Replicas),
+			fmt.Sprintf("completed steps: %v", is.CompletedSteps),
+			fmt.Sprintf("snapshot:        %s -> %s", is.SnapshotID, is.EncryptedSnapshotID),
+			fmt.Sprintf("unmatched:       %v", is.Unmatched),
+			fmt.Sprintf("rolled back:     %t", is.RolledBack),
+		}
+		for _, e := range is.Events {
+			lines = append(lines, fmt.Sprintf("  %s", e))
+		}
+		for _, l := range lines {
+			if _, err := fmt.Fprintln(w, l); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Done - is `step` already completed
+func (is *InstanceState) Done(step string) bool {
+	return contains(is.CompletedSteps, step)
+}
+
+func contains(a []string, s string) bool {
+	for _, v := range a {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,177 @@
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const tablesPrivQuery = `
+select
+    Host
+,   Db
+,   User
+,   Table_name
+,   Table_priv
+from mysql.tables_priv
+`
+
+const columnsPrivQuery = `
+select
+    Host
+,   Db
+,   User
+,   Table_name
+,   Column_name
+,   Column_priv
+from mysql.columns_priv
+`
+
+const procsPrivQuery = `
+select
+    Host
+,   Db
+,   User
+,   Routine_name
+,   Routine_type
+,   Proc_priv
+from mysql.procs_priv
+`
+
+// objectGrants - table, column and routine level grants (mysql.tables_priv,
+// mysql.columns_priv and mysql.procs_priv) as dumped by dumpQuery
+type objectGrants struct {
+	tables  map[string]map[string]*string
+	columns map[string]map[string]*string
+	procs   map[string]map[string]*string
+}
+
+func (i *Instance) dumpObjectGrants() (*objectGrants, error) {
+	g := &objectGrants{}
+	var err error
+
+	pk := map[string]interface{}{"Host": nil, "Db": nil, "User": nil, "Table_name": nil}
+	if g.tables, err = i.dumpQuery(tablesPrivQuery, pk); err != nil {
+		return nil, err
+	}
+
+	pk = map[string]interface{}{"Host": nil, "Db": nil, "User": nil, "Table_name": nil, "Column_name": nil}
+	if g.columns, err = i.dumpQuery(columnsPrivQuery, pk); err != nil {
+		return nil, err
+	}
+
+	pk = map[string]interface{}{"Host": nil, "Db": nil, "User": nil, "Routine_name": nil, "Routine_type": nil}
+	if g.procs, err = i.dumpQuery(procsPrivQuery, pk); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// grantCMDs - GRANT statements for every table, column and routine level grant of
+// `user`@`host`, in a stable order
+func (g *objectGrants) grantCMDs(host, user string) []string {
+	cmds := []string{}
+	to := fmt.Sprintf("'%s'@'%s'", quote(user), quote(host))
+
+	for _, row := range sortedRows(g.tables) {
+		if !isAccount(row, host, user) {
+			continue
+		}
+		privs, grantOpt := decodeSetPriv(row["Table_priv"])
+		if len(privs) == 0 {
+			// only column privileges, those come from columns_priv
+			continue
+		}
+		cmds = append(cmds, fmt.Sprintf("GRANT %s ON %s.%s TO %s%s",
+			strings.Join(privs, commaSep), quoteIdent(*row["Db"]), quoteIdent(*row["Table_name"]), to, grantOpt))
+	}
+
+	// one GRANT per table, ie: GRANT SELECT (`a`, `b`), UPDATE (`b`) ON `db`.`t` TO ...
+	type table struct{ db, name string }
+	tables := []table{}
+	columns := make(map[table]map[string][]string)
+	for _, row := range sortedRows(g.columns) {
+		if !isAccount(row, host, user) {
+			continue
+		}
+		t := table{*row["Db"], *row["Table_name"]}
+		if _, ok := columns[t]; !ok {
+			tables = append(tables, t)
+			columns[t] = make(map[string][]string)
+		}
+		privs, _ := decodeSetPriv(row["Column_priv"])
+		for _, p := range privs {
+			columns[t][p] = append(columns[t][p], quoteIdent(*row["Column_name"]))
+		}
+	}
+	for _, t := range tables {
+		names := []string{}
+		for p := range columns[t] {
+			names = append(names, p)
+		}
+		sort.Strings(names)
+
+		privs := []string{}
+		for _, p := range names {
+			privs = append(privs, fmt.Sprintf("%s (%s)", p, strings.Join(columns[t][p], commaSep)))
+		}
+		if len(privs) == 0 {
+			continue
+		}
+		cmds = append(cmds, fmt.Sprintf("GRANT %s ON %s.%s TO %s",
+			strings.Join(privs, commaSep), quoteIdent(t.db), quoteIdent(t.name), to))
+	}
+
+	for _, row := range sortedRows(g.procs) {
+		if !isAccount(row, host, user) {
+			continue
+		}
+		privs, grantOpt := decodeSetPriv(row["Proc_priv"])
+		if len(privs) == 0 {
+			continue
+		}
+		cmds = append(cmds, fmt.Sprintf("GRANT %s ON %s %s.%s TO %s%s",
+			strings.Join(privs, commaSep), *row["Routine_type"], quoteIdent(*row["Db"]), quoteIdent(*row["Routine_name"]), to, grantOpt))
+	}
+
+	return cmds
+}
+
+// decodeSetPriv - decodes a *_priv SET column (ie: "Select,Insert,Show view,Grant") to
+// it's GRANT privilege list, with Grant turned into WITH GRANT OPTION
+func decodeSetPriv(value *string) ([]string, string) {
+	privs, grantOpt := []string{}, ""
+	if value == nil || *value == "" {
+		return privs, grantOpt
+	}
+	for _, p := range strings.Split(*value, ",") {
+		if p == "Grant" {
+			grantOpt = decodePriv(grantPriv, &p)
+			continue
+		}
+		privs = append(privs, strings.ToUpper(p))
+	}
+	return privs, grantOpt
+}
+
+func isAccount(row map[string]*string, host, user string) bool {
+	return row["Host"] != nil && *row["Host"] == host && row["User"] != nil && *row["User"] == user
+}
+
+// sortedRows - dumpQuery result ordered by it's composite primary key
+func sortedRows(rows map[string]map[string]*string) []map[string]*string {
+	keys := []string{}
+	for k := range rows {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	sorted := []map[string]*string{}
+	for _, k := range keys {
+		sorted = append(sorted, rows[k])
+	}
+	return sorted
+}
+
+// quoteIdent - backtick quote a MySQL identifier
+func quoteIdent(s string) string {
+	return "`" + strings.Replace(s, "`", "``", -1) + "`"
+}
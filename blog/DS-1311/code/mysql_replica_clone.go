@@ -1,8 +1,10 @@
 import (
 	"database/sql"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,6 +18,7 @@ type mysqlReplicaClone struct {
 	copyTo       Instance
 	rootPass     string
 	binlogRetHrs *int
+	users        UserCloneOptions
 	log          *log.Logger
 }
 
@@ -23,8 +26,17 @@ const (
 	commaSep  = ", "
 	pkSep     = "|"
 	grantPriv = "Grant_priv"
+
+	binlogRetentionHours = "binlog retention hours"
 )
 
+const rdsConfigurationQuery = `
+select
+    name
+,   value
+from mysql.rds_configuration
+`
+
 const usersQuery = `
 select
     Host
@@ -90,34 +102,162 @@ select
 from mysql.db
 `
 
+var (
+	usersPK  = map[string]interface{}{"User": nil, "Host": nil}
+	grantsPK = map[string]interface{}{"Host": nil, "Db": nil, "User": nil}
+)
+
+// userPrivs - mysql.user *_priv columns (but Grant_priv), not all of them exist on every version
+var userPrivs = []string{
+	"Select_priv",
+	"Insert_priv",
+	"Update_priv",
+	"Delete_priv",
+	"Create_priv",
+	"Drop_priv",
+	"Reload_priv",
+	"Shutdown_priv",
+	"Process_priv",
+	"File_priv",
+	"References_priv",
+	"Index_priv",
+	"Alter_priv",
+	"Show_db_priv",
+	"Super_priv",
+	"Create_tmp_table_priv",
+	"Lock_tables_priv",
+	"Execute_priv",
+	"Repl_slave_priv",
+	"Repl_client_priv",
+	"Create_view_priv",
+	"Show_view_priv",
+	"Create_routine_priv",
+	"Alter_routine_priv",
+	"Create_user_priv",
+	"Event_priv",
+	"Trigger_priv",
+	"Create_tablespace_priv",
+	"Create_role_priv",
+	"Drop_role_priv",
+}
+
+// dbPrivs - mysql.db *_priv columns (but Grant_priv)
+var dbPrivs = []string{
+	"Select_priv",
+	"Insert_priv",
+	"Update_priv",
+	"Delete_priv",
+	"Create_priv",
+	"Drop_priv",
+	"References_priv",
+	"Index_priv",
+	"Alter_priv",
+	"Create_tmp_table_priv",
+	"Lock_tables_priv",
+	"Create_view_priv",
+	"Show_view_priv",
+	"Create_routine_priv",
+	"Alter_routine_priv",
+	"Execute_priv",
+	"Event_priv",
+	"Trigger_priv",
+}
+
 func (c *mysqlReplicaClone) execute(verbose, dryRun bool) error {
 	if err := c.copyFrom.connect("root", c.rootPass, "mysql"); err != nil {
 		return err
 	}
 	defer c.copyFrom.DB.Close()
 
+	src, err := c.dumpAccounts()
+	if err != nil {
+		return err
+	}
+
+	return c.apply(src, verbose, dryRun)
+}
+
+// dumpAccounts - users and grants of copyFrom (which has to be connected already)
+func (c *mysqlReplicaClone) dumpAccounts() (*AccountBundle, error) {
+	srcVersion, err := c.copyFrom.serverVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	b := &AccountBundle{
+		Version:              accountBundleVersion,
+		Source:               c.copyFrom.Name,
+		MySQLVersion:         srcVersion.String(),
+		ExportedAt:           time.Now().UTC(),
+		BinlogRetentionHours: c.binlogRetHrs,
+	}
+
+	if b.Users, err = c.copyFrom.dumpQuery(usersQueryFor(srcVersion), usersPK); err != nil {
+		return nil, err
+	}
+
+	if b.Grants, err = c.copyFrom.dumpQuery(grantsQuery, grantsPK); err != nil {
+		return nil, err
+	}
+
+	if b.RDSConfiguration, err = c.copyFrom.rdsConfiguration(); err != nil {
+		return nil, err
+	}
+	// the source's own retention, unless it's overridden
+	if hrs := b.RDSConfiguration[binlogRetentionHours]; b.BinlogRetentionHours == nil && hrs != nil {
+		n, err := strconv.Atoi(*hrs)
+		if err != nil {
+			return nil, fmt.Errorf("can't parse %s %q of %q: %v", binlogRetentionHours, *hrs, c.copyFrom.Name, err)
+		}
+		b.BinlogRetentionHours = &n
+	}
+
+	objectGrants, err := c.copyFrom.dumpObjectGrants()
+	if err != nil {
+		return nil, err
+	}
+	b.TablesPriv, b.ColumnsPriv, b.ProcsPriv = objectGrants.tables, objectGrants.columns, objectGrants.procs
+
+	return b, nil
+}
+
+// apply - create (and with users.Reconcile, reconcile) the accounts in `src` on copyTo
+func (c *mysqlReplicaClone) apply(src *AccountBundle, verbose, dryRun bool) error {
 	if err := c.copyTo.connect("root", c.rootPass, "mysql"); err != nil {
 		return err
 	}
 	defer c.copyTo.DB.Close()
 
-	usersPK := map[string]interface{}{"User": nil, "Host": nil}
-	srcUsers, err := c.copyFrom.dumpQuery(usersQuery, usersPK)
+	srcVersion, err := parseMySQLVersion(src.MySQLVersion)
 	if err != nil {
 		return err
 	}
-
-	grantsPK := map[string]interface{}{"Host": nil, "Db": nil, "User": nil}
-	srcGrants, err := c.copyFrom.dumpQuery(grantsQuery, grantsPK)
+	trgVersion, err := c.copyTo.serverVersion()
 	if err != nil {
 		return err
 	}
+	if !trgVersion.atLeast(srcVersion.major, srcVersion.minor) {
+		return fmt.Errorf("can't clone users from %q (MySQL %s) to older %q (MySQL %s)", src.Source, srcVersion, c.copyTo.Name, trgVersion)
+	}
+
+	srcUsers, srcGrants, srcObjectGrants := src.Users, src.Grants, src.objectGrants()
 
-	trgUsers, err := c.copyTo.dumpQuery(usersQuery, usersPK)
+	trgUsers, err := c.copyTo.dumpQuery(usersQueryFor(trgVersion), usersPK)
 	if err != nil {
 		return err
 	}
 
+	trgGrants := map[string]map[string]*string{}
+	trgObjectGrants := &objectGrants{}
+	if c.users.Reconcile {
+		if trgGrants, err = c.copyTo.dumpQuery(grantsQuery, grantsPK); err != nil {
+			return err
+		}
+		if trgObjectGrants, err = c.copyTo.dumpObjectGrants(); err != nil {
+			return err
+		}
+	}
+
 	// if verbose {
 	// 	spewConfig := spew.ConfigState{
 	// 		Indent:                  "\t",
@@ -131,18 +271,34 @@ func (c *mysqlReplicaClone) execute(verbose, dryRun bool) error {
 	// }
 
 	for user, privs := range srcUsers {
-		if _, ok := trgUsers[user]; ok {
+		if trgPrivs, ok := trgUsers[user]; ok {
+			if !c.users.Reconcile || systemUsers[*privs["User"]] {
+				continue
+			}
+			for _, cmd := range reconcileUserCMDs(privs, trgPrivs, srcGrants, trgGrants, srcObjectGrants, trgObjectGrants, trgVersion) {
+				c.log.Printf("... mysqlReplicaClone.execute: [%24s] reconciling %q user: %q", c.copyTo.Name, user, cmd)
+				if !dryRun {
+					if _, err := c.copyTo.DB.Exec(cmd); err != nil {
+						return err
+					}
+				}
+			}
+			continue
+		}
+		if systemUsers[*privs["User"]] {
 			continue
 		}
 
-		cmd := createUserCMD(privs)
-		c.log.Printf("... mysqlReplicaClone.execute: [%24s] creating %q user: %q", c.copyTo.Name, user, cmd)
+		cmds := createUserCMDsFor(privs, trgVersion)
 		if verbose {
 			spew.Dump(privs)
 		}
-		if !dryRun {
-			if _, err := c.copyTo.DB.Exec(cmd); err != nil {
-				return err
+		for _, cmd := range cmds {
+			c.log.Printf("... mysqlReplicaClone.execute: [%24s] creating %q user: %q", c.copyTo.Name, user, cmd)
+			if !dryRun {
+				if _, err := c.copyTo.DB.Exec(cmd); err != nil {
+					return err
+				}
 			}
 		}
 
@@ -167,49 +323,111 @@ func (c *mysqlReplicaClone) execute(verbose, dryRun bool) error {
 				}
 			}
 		}
+
+		// table, column and routine level grants
+		for _, cmd := range srcObjectGrants.grantCMDs(*privs["Host"], *privs["User"]) {
+			c.log.Printf("... mysqlReplicaClone.execute: [%24s] granting privs to %q user: %q", c.copyTo.Name, user, cmd)
+			if !dryRun {
+				if _, err := c.copyTo.DB.Exec(cmd); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if c.users.Reconcile && c.users.DropExtraUsers {
+		for user, privs := range trgUsers {
+			if _, ok := srcUsers[user]; ok {
+				continue
+			}
+			cmd := dropUserCMD(privs)
+			if cmd == "" {
+				continue
+			}
+			c.log.Printf("... mysqlReplicaClone.execute: [%24s] dropping %q user: %q", c.copyTo.Name, user, cmd)
+			if !dryRun {
+				if _, err := c.copyTo.DB.Exec(cmd); err != nil {
+					return err
+				}
+			}
+		}
 	}
 
-	return c.setBinlogRetention(verbose, dryRun)
+	return c.setRDSConfiguration(src.rdsConfiguration(), verbose, dryRun)
 }
 
+// setBinlogRetention - set binlogRetHrs (if any) on copyTo
 func (c *mysqlReplicaClone) setBinlogRetention(verbose, dryRun bool) error {
-	// getting multi-row results from stored procedure is not supported with this driver:
-	// see:
-	//	https://github.com/go-sql-driver/mysql/issues/66
-	//	https://github.com/golang/go/issues/12382
-	//
-	// pk := map[string]interface{}{"name": nil}
-	// conf, err := c.copyFrom.dumpQuery("call mysql.rds_show_configuration", pk)
-	// if err != nil {
-	// 	return fmt.Errorf("can't get binlog retention hours on %q: %v", c.copyFrom.Name, err)
-	// }
-
-	// row, ok := conf["binlog retention hours"]
-	// if verbose {
-	// 	spew.Dump(row)
-	// }
-	// if !ok {
-	// 	return nil
-	// }
+	if c.binlogRetHrs == nil {
+		return nil
+	}
+	hrs := strconv.Itoa(*c.binlogRetHrs)
+	return c.setRDSConfiguration(map[string]*string{binlogRetentionHours: &hrs}, verbose, dryRun)
+}
 
-	hrs := 168 // defaults to 7 days
-	if c.binlogRetHrs != nil {
-		hrs = *c.binlogRetHrs
+// setRDSConfiguration - replicate `want` (mysql.rds_configuration name -> value, nil
+// being NULL) onto copyTo, keys copyTo doesn't have or already has the same value are skipped
+func (c *mysqlReplicaClone) setRDSConfiguration(want map[string]*string, verbose, dryRun bool) error {
+	have, err := c.copyTo.rdsConfiguration()
+	if err != nil {
+		return err
 	}
-	cmd := fmt.Sprintf("call mysql.rds_set_configuration('binlog retention hours', %d)", hrs)
-	c.log.Printf("... mysqlReplicaClone.setBinlogRetention: [%24s] cmd: %q", c.copyTo.Name, cmd)
 
-	if dryRun {
-		return nil
+	names := []string{}
+	for name := range want {
+		names = append(names, name)
 	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value, ok := have[name]
+		if !ok {
+			c.log.Printf("... mysqlReplicaClone.setRDSConfiguration: [%24s] skipping %q, not supported", c.copyTo.Name, name)
+			continue
+		}
+		if configValue(value) == configValue(want[name]) {
+			continue
+		}
 
-	if _, err := c.copyTo.DB.Exec(cmd); err != nil {
-		return fmt.Errorf("can't set binlog retention hours on %q: %v", c.copyTo.Name, err)
+		cmd := fmt.Sprintf("call mysql.rds_set_configuration('%s', %s)", quote(name), configValue(want[name]))
+		c.log.Printf("... mysqlReplicaClone.setRDSConfiguration: [%24s] cmd: %q", c.copyTo.Name, cmd)
+		if dryRun {
+			continue
+		}
+		if _, err := c.copyTo.DB.Exec("call mysql.rds_set_configuration(?, ?)", name, want[name]); err != nil {
+			return fmt.Errorf("can't set %s on %q: %v", name, c.copyTo.Name, err)
+		}
 	}
 
 	return nil
 }
 
+// rdsConfiguration - mysql.rds_configuration name -> value, read directly since the driver
+// can't get the multi-row results of mysql.rds_show_configuration, see:
+//
+//	https://github.com/go-sql-driver/mysql/issues/66
+func (i *Instance) rdsConfiguration() (map[string]*string, error) {
+	rows, err := i.dumpQuery(rdsConfigurationQuery, map[string]interface{}{"name": nil})
+	if err != nil {
+		return nil, fmt.Errorf("can't get RDS configuration of %q: %v", i.Name, err)
+	}
+
+	conf := make(map[string]*string)
+	for _, row := range rows {
+		conf[*row["name"]] = row["value"]
+	}
+	return conf, nil
+}
+
+// configValue - rds_configuration value as an SQL literal, the same way the
+// driver interpolates it for rds_set_configuration
+func configValue(v *string) string {
+	if v == nil {
+		return "NULL"
+	}
+	return "'" + quote(*v) + "'"
+}
+
 func hasGrants(grantKey, host, user string) bool {
 	if strings.HasPrefix(grantKey, host+pkSep) && strings.HasSuffix(grantKey, pkSep+user+pkSep) {
 		return true
@@ -217,12 +435,15 @@ func hasGrants(grantKey, host, user string) bool {
 	return false
 }
 
+// mysqlDriver - database/sql driver connect uses, swapped out in tests
+var mysqlDriver = "mysql"
+
 func (i *Instance) connect(user, pass, schema string) error {
 	host := *i.RDSDBInstance.Endpoint.Address
 	port := *i.RDSDBInstance.Endpoint.Port
 	conn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?interpolateParams=true", user, pass, host, port, schema)
 	mskd := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?interpolateParams=true", user, "*******", host, port, schema)
-	db, err := sql.Open("mysql", conn)
+	db, err := sql.Open(mysqlDriver, conn)
 	if err != nil {
 		return fmt.Errorf("ERROR: connecting to %s: %v", mskd, err)
 	}
@@ -323,43 +544,85 @@ func (i *Instance) dumpQuery(query string, pk map[string]interface{}) (result ma
 	return result, nil
 }
 
+// createUserCMDsFor - createUserCMDs on 5.7+ `v`, createUserCMD before that
+func createUserCMDsFor(privs map[string]*string, v mysqlVersion) []string {
+	if v.hasAuthString() {
+		return createUserCMDs(privs, v)
+	}
+	return []string{createUserCMD(privs)}
+}
+
+// createUserCMD - pre 5.7 single GRANT that also creates the user
 func createUserCMD(privs map[string]*string) string {
-	allPrivs := []string{
-		"Select_priv",
-		"Insert_priv",
-		"Update_priv",
-		"Delete_priv",
-		"Create_priv",
-		"Drop_priv",
-		"Reload_priv",
-		"Shutdown_priv",
-		"Process_priv",
-		"File_priv",
-		"References_priv",
-		"Index_priv",
-		"Alter_priv",
-		"Show_db_priv",
-		"Super_priv",
-		"Create_tmp_table_priv",
-		"Lock_tables_priv",
-		"Execute_priv",
-		"Repl_slave_priv",
-		"Repl_client_priv",
-		"Create_view_priv",
-		"Show_view_priv",
-		"Create_routine_priv",
-		"Alter_routine_priv",
-		"Create_user_priv",
-		"Event_priv",
-		"Trigger_priv",
-		"Create_tablespace_priv",
+	return fmt.Sprintf("GRANT %s ON *.* TO '%s'@'%s' IDENTIFIED BY PASSWORD '%s'%s",
+		globalPrivsCMD(privs),
+		*privs["User"],
+		*privs["Host"],
+		*privs["Password"],
+		decodePriv(grantPriv, privs[grantPriv]),
+	)
+}
+
+// createUserCMDs - 5.7+ CREATE USER with the authentication plugin and hash followed
+// by a separate GRANT, a pre 5.7 source only has the (native) Password hash
+func createUserCMDs(privs map[string]*string, v mysqlVersion) []string {
+	plugin, hash := credentials(privs)
+	user, host := quote(*privs["User"]), quote(*privs["Host"])
+	return []string{
+		fmt.Sprintf("CREATE USER '%s'@'%s' IDENTIFIED WITH %s AS %s", user, host, plugin, hashLiteral(hash, v)),
+		fmt.Sprintf("GRANT %s ON *.* TO '%s'@'%s'%s", globalPrivsCMD(privs), user, host, decodePriv(grantPriv, privs[grantPriv])),
+	}
+}
+
+// hashLiteral - authentication_string as an SQL literal for `v`, binary hashes (ie:
+// caching_sha2_password) don't survive quoting as a string so they're hex encoded
+// where `v` accepts that, everything else is quoted
+func hashLiteral(hash string, v mysqlVersion) string {
+	if !v.hasHexAuthString() || printable(hash) {
+		return "'" + quote(hash) + "'"
+	}
+	return "0x" + hex.EncodeToString([]byte(hash))
+}
+
+// printable - `s` is nothing but printable ASCII
+func printable(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x20 || s[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// credentials - authentication plugin and hash of a mysql.user row, a pre 5.7
+// row only has the (native) Password hash
+func credentials(privs map[string]*string) (string, string) {
+	plugin, hash := nativePassword, ""
+	if v, ok := privs["plugin"]; ok && v != nil && *v != "" {
+		plugin = *v
+	}
+	if v, ok := privs["authentication_string"]; ok && v != nil {
+		hash = *v
+	} else if v, ok := privs["Password"]; ok && v != nil {
+		hash = *v
 	}
+	return plugin, hash
+}
+
+// globalPrivsCMD - mysql.user *_priv columns as a GRANT privilege list, only the
+// columns the server has count towards ALL PRIVILEGES (8.0 adds the role ones)
+func globalPrivsCMD(privs map[string]*string) string {
+	allPrivs := append([]string{}, userPrivs...)
 
 	sort.Strings(allPrivs)
 
 	cmd := ""
-	cnt := 0
+	cnt, present := 0, 0
 	for _, name := range allPrivs {
+		if _, ok := privs[name]; !ok {
+			continue
+		}
+		present++
 		c := decodePriv(name, privs[name])
 		if c == "" {
 			continue
@@ -369,44 +632,19 @@ func createUserCMD(privs map[string]*string) string {
 	}
 
 	switch cnt {
-	case len(allPrivs):
-		cmd = "ALL PRIVILEGES"
 	case 0:
 		cmd = "USAGE"
+	case present:
+		cmd = "ALL PRIVILEGES"
 	default:
 		cmd = strings.TrimSuffix(cmd, commaSep)
 	}
 
-	return fmt.Sprintf("GRANT %s ON *.* TO '%s'@'%s' IDENTIFIED BY PASSWORD '%s'%s",
-		cmd,
-		*privs["User"],
-		*privs["Host"],
-		*privs["Password"],
-		decodePriv(grantPriv, privs[grantPriv]),
-	)
+	return cmd
 }
 
 func giveGrantsCMD(privs map[string]*string) string {
-	allPrivs := []string{
-		"Select_priv",
-		"Insert_priv",
-		"Update_priv",
-		"Delete_priv",
-		"Create_priv",
-		"Drop_priv",
-		"References_priv",
-		"Index_priv",
-		"Alter_priv",
-		"Create_tmp_table_priv",
-		"Lock_tables_priv",
-		"Create_view_priv",
-		"Show_view_priv",
-		"Create_routine_priv",
-		"Alter_routine_priv",
-		"Execute_priv",
-		"Event_priv",
-		"Trigger_priv",
-	}
+	allPrivs := append([]string{}, dbPrivs...)
 
 	sort.Strings(allPrivs)
 
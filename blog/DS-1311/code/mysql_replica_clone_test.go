@@ -0,0 +1,79 @@
+import (
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// nativeHash - mysql_native_password hash, printable
+const nativeHash = "*2470C0C06DEE42FD1618BB99005ADCA2EC9D1E19"
+
+func TestCreateUserCMDsFor(t *testing.T) {
+	user := func(plugin, hash string) map[string]*string {
+		return map[string]*string{
+			"Host":                  aws.String("%"),
+			"User":                  aws.String("app"),
+			"plugin":                aws.String(plugin),
+			"authentication_string": aws.String(hash),
+			"Select_priv":           aws.String("Y"),
+			"Insert_priv":           aws.String("N"),
+			"Grant_priv":            aws.String("N"),
+		}
+	}
+	grant := "GRANT SELECT ON *.* TO 'app'@'%'"
+
+	for _, tc := range []struct {
+		name    string
+		version string
+		privs   map[string]*string
+		want    []string
+	}{
+		{
+			name:    "5.7 native",
+			version: "5.7.26-log",
+			privs:   user(nativePassword, nativeHash),
+			want:    []string{"CREATE USER 'app'@'%' IDENTIFIED WITH mysql_native_password AS '" + nativeHash + "'", grant},
+		},
+		{
+			name:    "5.7 binary hash is quoted, no hex literals before 8.0.17",
+			version: "5.7.26",
+			privs:   user("sha256_password", binaryHash),
+			want:    []string{"CREATE USER 'app'@'%' IDENTIFIED WITH sha256_password AS '" + quote(binaryHash) + "'", grant},
+		},
+		{
+			name:    "8.0.16 binary hash is quoted",
+			version: "8.0.16",
+			privs:   user("caching_sha2_password", binaryHash),
+			want:    []string{"CREATE USER 'app'@'%' IDENTIFIED WITH caching_sha2_password AS '" + quote(binaryHash) + "'", grant},
+		},
+		{
+			name:    "8.0.28 native",
+			version: "8.0.28",
+			privs:   user(nativePassword, nativeHash),
+			want:    []string{"CREATE USER 'app'@'%' IDENTIFIED WITH mysql_native_password AS '" + nativeHash + "'", grant},
+		},
+		{
+			name:    "8.0.28 binary hash",
+			version: "8.0.28",
+			privs:   user("caching_sha2_password", binaryHash),
+			want:    []string{"CREATE USER 'app'@'%' IDENTIFIED WITH caching_sha2_password AS 0x" + hex.EncodeToString([]byte(binaryHash)), grant},
+		},
+		{
+			name:    "8.0.28 no password",
+			version: "8.0.28",
+			privs:   user("caching_sha2_password", ""),
+			want:    []string{"CREATE USER 'app'@'%' IDENTIFIED WITH caching_sha2_password AS ''", grant},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := parseMySQLVersion(tc.version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := createUserCMDsFor(tc.privs, v); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("createUserCMDsFor(%s) =\n%q\nwant\n%q", tc.version, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// UserCloneOptions - how mysqlReplicaClone treats users that already exist on the target
+type UserCloneOptions struct {
+	// Reconcile - make the privileges (and password) of users that exist on both ends
+	// match the source with GRANT/REVOKE, by default existing users are left alone
+	Reconcile bool
+
+	// DropExtraUsers - with Reconcile, also drop users that only exist on the target
+	DropExtraUsers bool
+}
+
+// systemUsers - accounts RDS or MySQL own, never created, reconciled or dropped
+// (rdsrepladmin only exists on instances that have, or had, read replicas)
+var systemUsers = map[string]bool{
+	"rdsadmin":         true,
+	"rdsrepladmin":     true,
+	"mysql.sys":        true,
+	"mysql.session":    true,
+	"mysql.infoschema": true,
+}
+
+// reconcileUserCMDs - statements that bring the existing target account `trg` in line
+// with the source's `src` (both mysql.user rows), it's schema grants `trgGrants` with
+// `srcGrants` (both mysql.db dumps) and it's table, column and routine grants `trgObjects`
+// with `srcObjects`
+func reconcileUserCMDs(src, trg map[string]*string, srcGrants, trgGrants map[string]map[string]*string, srcObjects, trgObjects *objectGrants, trgVersion mysqlVersion) []string {
+	host, user := *src["Host"], *src["User"]
+	to := fmt.Sprintf("'%s'@'%s'", quote(user), quote(host))
+	cmds := []string{}
+
+	srcPlugin, srcHash := credentials(src)
+	trgPlugin, trgHash := credentials(trg)
+	if srcPlugin != trgPlugin || srcHash != trgHash {
+		if trgVersion.hasAuthString() {
+			cmds = append(cmds, fmt.Sprintf("ALTER USER %s IDENTIFIED WITH %s AS %s", to, srcPlugin, hashLiteral(srcHash, trgVersion)))
+		} else {
+			cmds = append(cmds, fmt.Sprintf("SET PASSWORD FOR %s = '%s'", to, quote(srcHash)))
+		}
+	}
+
+	cmds = append(cmds, privDriftCMDs(userPrivs, src, trg, "*.*", to)...)
+
+	// schema grants, on either end
+	for _, key := range accountKeys(srcGrants, trgGrants, host, user) {
+		row := eitherRow(srcGrants, trgGrants, key)
+		on := quoteIdent(*row["Db"]) + ".*"
+		cmds = append(cmds, privDriftCMDs(dbPrivs, srcGrants[key], trgGrants[key], on, to)...)
+	}
+
+	// table, column and routine grants, on either end
+	for _, key := range accountKeys(srcObjects.tables, trgObjects.tables, host, user) {
+		row := eitherRow(srcObjects.tables, trgObjects.tables, key)
+		on := quoteIdent(*row["Db"]) + "." + quoteIdent(*row["Table_name"])
+		cmds = append(cmds, setPrivDriftCMDs(column(srcObjects.tables[key], "Table_priv"), column(trgObjects.tables[key], "Table_priv"), "", on, to)...)
+	}
+	for _, key := range accountKeys(srcObjects.columns, trgObjects.columns, host, user) {
+		row := eitherRow(srcObjects.columns, trgObjects.columns, key)
+		on := quoteIdent(*row["Db"]) + "." + quoteIdent(*row["Table_name"])
+		cmds = append(cmds, setPrivDriftCMDs(column(srcObjects.columns[key], "Column_priv"), column(trgObjects.columns[key], "Column_priv"),
+			quoteIdent(*row["Column_name"]), on, to)...)
+	}
+	for _, key := range accountKeys(srcObjects.procs, trgObjects.procs, host, user) {
+		row := eitherRow(srcObjects.procs, trgObjects.procs, key)
+		on := *row["Routine_type"] + " " + quoteIdent(*row["Db"]) + "." + quoteIdent(*row["Routine_name"])
+		cmds = append(cmds, setPrivDriftCMDs(column(srcObjects.procs[key], "Proc_priv"), column(trgObjects.procs[key], "Proc_priv"), "", on, to)...)
+	}
+
+	return cmds
+}
+
+// accountKeys - sorted keys of the `host`/`user` rows in either `src` or `trg`
+func accountKeys(src, trg map[string]map[string]*string, host, user string) []string {
+	keys := map[string]bool{}
+	for _, rows := range []map[string]map[string]*string{src, trg} {
+		for key, row := range rows {
+			if isAccount(row, host, user) {
+				keys[key] = true
+			}
+		}
+	}
+	sorted := []string{}
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+func eitherRow(src, trg map[string]map[string]*string, key string) map[string]*string {
+	if row := src[key]; row != nil {
+		return row
+	}
+	return trg[key]
+}
+
+// column - `col` of `row`, nil if there is no such row
+func column(row map[string]*string, col string) *string {
+	if row == nil {
+		return nil
+	}
+	return row[col]
+}
+
+// setPrivDriftCMDs - privDriftCMDs for a *_priv SET column (tables_priv, columns_priv and
+// procs_priv), `src` and `trg` are the column values (nil if the row is missing) and
+// `col` is the column the privileges are limited to ("" if they aren't)
+func setPrivDriftCMDs(src, trg *string, col, on, to string) []string {
+	srcPrivs, srcGrantOpt := decodeSetPriv(src)
+	trgPrivs, trgGrantOpt := decodeSetPriv(trg)
+
+	minus := func(a, b []string) []string {
+		diff := []string{}
+		for _, p := range a {
+			if !contains(b, p) {
+				if col != "" {
+					p = fmt.Sprintf("%s (%s)", p, col)
+				}
+				diff = append(diff, p)
+			}
+		}
+		return diff
+	}
+
+	cmds := []string{}
+	grant := minus(srcPrivs, trgPrivs)
+	if srcGrantOpt != "" && trgGrantOpt == "" && len(grant) == 0 {
+		// GRANT OPTION on it's own, re-granting what's already there is a no-op
+		grant = minus(srcPrivs, nil)
+	}
+	if len(grant) > 0 {
+		grantOpt := ""
+		if trgGrantOpt == "" {
+			grantOpt = srcGrantOpt
+		}
+		cmds = append(cmds, fmt.Sprintf("GRANT %s ON %s TO %s%s", strings.Join(grant, commaSep), on, to, grantOpt))
+	}
+	if revoke := minus(trgPrivs, srcPrivs); len(revoke) > 0 {
+		cmds = append(cmds, fmt.Sprintf("REVOKE %s ON %s FROM %s", strings.Join(revoke, commaSep), on, to))
+	}
+	if srcGrantOpt == "" && trgGrantOpt != "" {
+		cmds = append(cmds, fmt.Sprintf("REVOKE GRANT OPTION ON %s FROM %s", on, to))
+	}
+	return cmds
+}
+
+// privDriftCMDs - GRANT/REVOKE for the `cols` *_priv columns (and Grant_priv) that differ
+// between the `src` and `trg` rows ON `on`, a nil row has no privileges, columns the
+// source doesn't have (ie: 8.0 only ones) are left alone
+func privDriftCMDs(cols []string, src, trg map[string]*string, on, to string) []string {
+	isSet := func(row map[string]*string, col string) bool {
+		return row != nil && row[col] != nil && *row[col] == "Y"
+	}
+	has := func(col string) bool {
+		if src != nil {
+			_, ok := src[col]
+			return ok
+		}
+		_, ok := trg[col]
+		return ok
+	}
+
+	allPrivs := append([]string{}, cols...)
+	sort.Strings(allPrivs)
+
+	grant, revoke := []string{}, []string{}
+	for _, col := range allPrivs {
+		if !has(col) {
+			continue
+		}
+		switch s, t := isSet(src, col), isSet(trg, col); {
+		case s && !t:
+			grant = append(grant, decodePriv(col, src[col]))
+		case !s && t:
+			revoke = append(revoke, decodePriv(col, trg[col]))
+		}
+	}
+
+	cmds := []string{}
+	if len(grant) > 0 {
+		cmds = append(cmds, fmt.Sprintf("GRANT %s ON %s TO %s", strings.Join(grant, commaSep), on, to))
+	}
+	if len(revoke) > 0 {
+		cmds = append(cmds, fmt.Sprintf("REVOKE %s ON %s FROM %s", strings.Join(revoke, commaSep), on, to))
+	}
+	switch s, t := isSet(src, grantPriv), isSet(trg, grantPriv); {
+	case s && !t:
+		cmds = append(cmds, fmt.Sprintf("GRANT USAGE ON %s TO %s%s", on, to, decodePriv(grantPriv, src[grantPriv])))
+	case !s && t:
+		cmds = append(cmds, fmt.Sprintf("REVOKE GRANT OPTION ON %s FROM %s", on, to))
+	}
+	return cmds
+}
+
+// dropUserCMD - for users that only exist on the target, nothing for system users
+func dropUserCMD(privs map[string]*string) string {
+	if systemUsers[*privs["User"]] {
+		return ""
+	}
+	return fmt.Sprintf("DROP USER '%s'@'%s'", quote(*privs["User"]), quote(*privs["Host"]))
+}
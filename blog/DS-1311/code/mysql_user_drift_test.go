@@ -0,0 +1,253 @@
+import (
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// privRow - dumpQuery row from column/value pairs
+func privRow(kv ...string) map[string]*string {
+	r := map[string]*string{}
+	for n := 0; n+1 < len(kv); n += 2 {
+		r[kv[n]] = aws.String(kv[n+1])
+	}
+	return r
+}
+
+// appAccount - the account the statements are for
+const appAccount = "'app'@'%'"
+
+func TestPrivDriftCMDs(t *testing.T) {
+	cols := []string{"Select_priv", "Insert_priv", "Update_priv"}
+
+	for _, tc := range []struct {
+		name     string
+		src, trg map[string]*string
+		want     []string
+	}{
+		{
+			name: "in sync",
+			src:  privRow("Select_priv", "Y", "Insert_priv", "N", "Update_priv", "N"),
+			trg:  privRow("Select_priv", "Y", "Insert_priv", "N", "Update_priv", "N"),
+			want: []string{},
+		},
+		{
+			name: "missing and extra privileges",
+			src:  privRow("Select_priv", "Y", "Insert_priv", "Y", "Update_priv", "N"),
+			trg:  privRow("Select_priv", "N", "Insert_priv", "N", "Update_priv", "Y"),
+			want: []string{"GRANT INSERT, SELECT ON *.* TO " + appAccount, "REVOKE UPDATE ON *.* FROM " + appAccount},
+		},
+		{
+			name: "only on the source",
+			src:  privRow("Select_priv", "Y", "Insert_priv", "N", "Update_priv", "Y"),
+			want: []string{"GRANT SELECT, UPDATE ON *.* TO " + appAccount},
+		},
+		{
+			name: "only on the target",
+			trg:  privRow("Select_priv", "Y", "Insert_priv", "N", "Update_priv", "N"),
+			want: []string{"REVOKE SELECT ON *.* FROM " + appAccount},
+		},
+		{
+			name: "columns the source doesn't have are left alone",
+			src:  privRow("Select_priv", "Y", "Insert_priv", "N"),
+			trg:  privRow("Select_priv", "Y", "Insert_priv", "N", "Update_priv", "Y"),
+			want: []string{},
+		},
+		{
+			name: "grant option granted",
+			src:  privRow("Select_priv", "Y", "Grant_priv", "Y"),
+			trg:  privRow("Select_priv", "Y", "Grant_priv", "N"),
+			want: []string{"GRANT USAGE ON *.* TO " + appAccount + " WITH GRANT OPTION"},
+		},
+		{
+			name: "grant option revoked",
+			src:  privRow("Select_priv", "Y", "Grant_priv", "N"),
+			trg:  privRow("Select_priv", "Y", "Grant_priv", "Y"),
+			want: []string{"REVOKE GRANT OPTION ON *.* FROM " + appAccount},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := privDriftCMDs(cols, tc.src, tc.trg, "*.*", appAccount); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("privDriftCMDs() =\n%q\nwant\n%q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSetPrivDriftCMDs(t *testing.T) {
+	const on = "`app`.`orders`"
+
+	for _, tc := range []struct {
+		name     string
+		src, trg *string
+		col      string
+		want     []string
+	}{
+		{
+			name: "in sync",
+			src:  aws.String("Select,Insert"),
+			trg:  aws.String("Select,Insert"),
+			want: []string{},
+		},
+		{
+			name: "only on the source",
+			src:  aws.String("Select,Create View"),
+			want: []string{"GRANT SELECT, CREATE VIEW ON " + on + " TO " + appAccount},
+		},
+		{
+			name: "only on the target",
+			trg:  aws.String("Select,Insert"),
+			want: []string{"REVOKE SELECT, INSERT ON " + on + " FROM " + appAccount},
+		},
+		{
+			name: "missing and extra privileges",
+			src:  aws.String("Select"),
+			trg:  aws.String("Update"),
+			want: []string{"GRANT SELECT ON " + on + " TO " + appAccount, "REVOKE UPDATE ON " + on + " FROM " + appAccount},
+		},
+		{
+			name: "grant option with missing privileges",
+			src:  aws.String("Select,Insert,Grant"),
+			trg:  aws.String("Select"),
+			want: []string{"GRANT INSERT ON " + on + " TO " + appAccount + " WITH GRANT OPTION"},
+		},
+		{
+			name: "grant option on it's own",
+			src:  aws.String("Select,Grant"),
+			trg:  aws.String("Select"),
+			want: []string{"GRANT SELECT ON " + on + " TO " + appAccount + " WITH GRANT OPTION"},
+		},
+		{
+			name: "grant option revoked",
+			src:  aws.String("Select"),
+			trg:  aws.String("Select,Grant"),
+			want: []string{"REVOKE GRANT OPTION ON " + on + " FROM " + appAccount},
+		},
+		{
+			name: "column privileges",
+			src:  aws.String("Select,Update"),
+			trg:  aws.String("Select,Insert"),
+			col:  "`id`",
+			want: []string{"GRANT UPDATE (`id`) ON " + on + " TO " + appAccount, "REVOKE INSERT (`id`) ON " + on + " FROM " + appAccount},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := setPrivDriftCMDs(tc.src, tc.trg, tc.col, on, appAccount); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("setPrivDriftCMDs() =\n%q\nwant\n%q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestReconcileUserCMDs(t *testing.T) {
+	user := func(kv ...string) map[string]*string {
+		return privRow(append([]string{"Host", "%", "User", "app", "Select_priv", "Y", "Insert_priv", "N", "Grant_priv", "N"}, kv...)...)
+	}
+
+	for _, tc := range []struct {
+		name                 string
+		version              string
+		src, trg             map[string]*string
+		srcGrants, trgGrants map[string]map[string]*string
+		srcObjects           *objectGrants
+		trgObjects           *objectGrants
+		want                 []string
+	}{
+		{
+			name:    "in sync",
+			version: "8.0.28",
+			src:     user("plugin", nativePassword, "authentication_string", nativeHash),
+			trg:     user("plugin", nativePassword, "authentication_string", nativeHash),
+			want:    []string{},
+		},
+		{
+			name:    "5.6 password",
+			version: "5.6.51",
+			src:     user("Password", nativeHash),
+			trg:     user("Password", "*0000000000000000000000000000000000000000"),
+			want:    []string{"SET PASSWORD FOR " + appAccount + " = '" + nativeHash + "'"},
+		},
+		{
+			name:    "5.7 password and plugin",
+			version: "5.7.26",
+			src:     user("plugin", "sha256_password", "authentication_string", binaryHash),
+			trg:     user("plugin", nativePassword, "authentication_string", nativeHash),
+			want:    []string{"ALTER USER " + appAccount + " IDENTIFIED WITH sha256_password AS '" + quote(binaryHash) + "'"},
+		},
+		{
+			name:    "8.0.28 password and plugin",
+			version: "8.0.28",
+			src:     user("plugin", "caching_sha2_password", "authentication_string", binaryHash),
+			trg:     user("plugin", nativePassword, "authentication_string", nativeHash),
+			want:    []string{"ALTER USER " + appAccount + " IDENTIFIED WITH caching_sha2_password AS 0x" + hex.EncodeToString([]byte(binaryHash))},
+		},
+		{
+			name:    "global, schema and object privileges",
+			version: "8.0.28",
+			src:     user("plugin", nativePassword, "authentication_string", nativeHash),
+			trg:     user("plugin", nativePassword, "authentication_string", nativeHash, "Select_priv", "N", "Insert_priv", "Y"),
+			srcGrants: map[string]map[string]*string{
+				"app": privRow("Host", "%", "User", "app", "Db", "app", "Select_priv", "Y"),
+			},
+			trgGrants: map[string]map[string]*string{
+				"old":     privRow("Host", "%", "User", "app", "Db", "old", "Select_priv", "Y"),
+				"someone": privRow("Host", "%", "User", "someone", "Db", "app", "Select_priv", "Y"),
+			},
+			srcObjects: &objectGrants{
+				tables: map[string]map[string]*string{
+					"orders": privRow("Host", "%", "User", "app", "Db", "app", "Table_name", "orders", "Table_priv", "Select,Update"),
+				},
+				procs: map[string]map[string]*string{
+					"p": privRow("Host", "%", "User", "app", "Db", "app", "Routine_name", "p", "Routine_type", "PROCEDURE", "Proc_priv", "Execute"),
+				},
+			},
+			trgObjects: &objectGrants{
+				columns: map[string]map[string]*string{
+					"orders.id": privRow("Host", "%", "User", "app", "Db", "app", "Table_name", "orders", "Column_name", "id", "Column_priv", "Insert"),
+				},
+				procs: map[string]map[string]*string{
+					"p": privRow("Host", "%", "User", "app", "Db", "app", "Routine_name", "p", "Routine_type", "PROCEDURE", "Proc_priv", "Execute,Grant"),
+				},
+			},
+			want: []string{
+				"GRANT SELECT ON *.* TO " + appAccount,
+				"REVOKE INSERT ON *.* FROM " + appAccount,
+				"GRANT SELECT ON `app`.* TO " + appAccount,
+				"REVOKE SELECT ON `old`.* FROM " + appAccount,
+				"GRANT SELECT, UPDATE ON `app`.`orders` TO " + appAccount,
+				"REVOKE INSERT (`id`) ON `app`.`orders` FROM " + appAccount,
+				"REVOKE GRANT OPTION ON PROCEDURE `app`.`p` FROM " + appAccount,
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := parseMySQLVersion(tc.version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			srcObjects, trgObjects := tc.srcObjects, tc.trgObjects
+			if srcObjects == nil {
+				srcObjects = &objectGrants{}
+			}
+			if trgObjects == nil {
+				trgObjects = &objectGrants{}
+			}
+			got := reconcileUserCMDs(tc.src, tc.trg, tc.srcGrants, tc.trgGrants, srcObjects, trgObjects, v)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("reconcileUserCMDs(%s) =\n%q\nwant\n%q", tc.version, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSystemUsersAreNeverDropped(t *testing.T) {
+	for _, user := range []string{"rdsadmin", "rdsrepladmin", "mysql.sys"} {
+		if cmd := dropUserCMD(privRow("Host", "localhost", "User", user)); cmd != "" {
+			t.Errorf("dropUserCMD(%s) = %q, want nothing", user, cmd)
+		}
+	}
+	if cmd, want := dropUserCMD(privRow("Host", "%", "User", "app")), "DROP USER "+appAccount; cmd != want {
+		t.Errorf("dropUserCMD(app) = %q, want %q", cmd, want)
+	}
+}
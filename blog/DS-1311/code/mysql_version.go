@@ -0,0 +1,73 @@
+import (
+	"fmt"
+	"strings"
+)
+
+const nativePassword = "mysql_native_password"
+
+// mysqlVersion - major.minor.patch of a MySQL server as reported by `select version()`
+type mysqlVersion struct {
+	raw   string
+	major int
+	minor int
+	patch int
+}
+
+func (v mysqlVersion) String() string {
+	return v.raw
+}
+
+func (v mysqlVersion) atLeast(major, minor int) bool {
+	return v.major > major || (v.major == major && v.minor >= minor)
+}
+
+// hasAuthString - 5.7 moved the password hash from mysql.user.Password to
+// authentication_string (next to plugin), and 8.0 removed GRANT ... IDENTIFIED BY PASSWORD
+func (v mysqlVersion) hasAuthString() bool {
+	return v.atLeast(5, 7)
+}
+
+// hasRoles - 8.0 added the CREATE ROLE and DROP ROLE privileges to mysql.user
+func (v mysqlVersion) hasRoles() bool {
+	return v.atLeast(8, 0)
+}
+
+// hasHexAuthString - 8.0.17 accepts a hex literal after IDENTIFIED WITH ... AS,
+// before that it has to be a quoted string
+func (v mysqlVersion) hasHexAuthString() bool {
+	return v.atLeast(8, 1) || (v.atLeast(8, 0) && v.patch >= 17)
+}
+
+// parseMySQLVersion - ie: 5.7.26-log or 8.0.23, the patch level is optional
+func parseMySQLVersion(raw string) (mysqlVersion, error) {
+	v := mysqlVersion{raw: raw}
+	if n, err := fmt.Sscanf(raw, "%d.%d.%d", &v.major, &v.minor, &v.patch); n < 2 {
+		return mysqlVersion{}, fmt.Errorf("can't parse MySQL version %q: %v", raw, err)
+	}
+	return v, nil
+}
+
+func (i *Instance) serverVersion() (mysqlVersion, error) {
+	var raw string
+	if err := i.DB.QueryRow("select version()").Scan(&raw); err != nil {
+		return mysqlVersion{}, fmt.Errorf("can't get MySQL version of %q: %v", i.Name, err)
+	}
+	return parseMySQLVersion(raw)
+}
+
+// usersQueryFor - usersQuery with the columns mysql.user has on `v`
+func usersQueryFor(v mysqlVersion) string {
+	q := usersQuery
+	if v.hasAuthString() {
+		q = strings.Replace(q, ",   Password\n", ",   authentication_string\n,   plugin\n", 1)
+	}
+	if v.hasRoles() {
+		q = strings.Replace(q, ",   Create_tablespace_priv\n", ",   Create_tablespace_priv\n,   Create_role_priv\n,   Drop_role_priv\n", 1)
+	}
+	return q
+}
+
+// quote - escape `s` for use inside a single quoted MySQL string literal
+func quote(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\x00", `\0`).Replace(s)
+}
@@ -0,0 +1,242 @@
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+// ModifyDBParameterGroup and ResetDBParameterGroup take at most 20 parameters per call
+const maxParametersPerCall = 20
+
+// ParameterGroupRef - a DB parameter group, possibly in another region/account
+// (nil SDK means the one it's used with)
+type ParameterGroupRef struct {
+	SDK  *SDK
+	Name string
+}
+
+// ParameterDiff - a user-modified parameter that differs between two DB parameter groups,
+// Value is what it should be set to (nil to reset it to the engine default) and Current
+// is what it's set to now (nil if it's the engine default)
+type ParameterDiff struct {
+	Name      string
+	Value     *string
+	Current   *string
+	ApplyType string // "static" parameters only take effect after a reboot
+}
+
+func (d ParameterDiff) String() string {
+	pp := func(v *string) string {
+		if v == nil {
+			return "<default>"
+		}
+		return fmt.Sprintf("%q", *v)
+	}
+	return fmt.Sprintf("%s: %s -> %s (%s)", d.Name, pp(d.Current), pp(d.Value), d.ApplyType)
+}
+
+// applyMethod - what ModifyDBParameterGroup/ResetDBParameterGroup want for this parameter
+func (d ParameterDiff) applyMethod() string {
+	if d.ApplyType == "static" {
+		return rds.ApplyMethodPendingReboot
+	}
+	return rds.ApplyMethodImmediate
+}
+
+// userParameters - parameters of `group` that were changed from the engine default
+func (s *SDK) userParameters(group string) (map[string]*rds.Parameter, error) {
+	params := make(map[string]*rds.Parameter)
+	if err := s.svc.DescribeDBParametersPagesWithContext(s.ctx, &rds.DescribeDBParametersInput{
+		DBParameterGroupName: aws.String(group),
+		Source:               aws.String("user"),
+	}, func(page *rds.DescribeDBParametersOutput, lastPage bool) bool {
+		for _, p := range page.Parameters {
+			params[aws.StringValue(p.ParameterName)] = p
+		}
+		return true
+	}); err != nil {
+		return nil, fmt.Errorf("ERROR: DescribeDBParametersPagesWithContext(%s) failed with: %v", group, err)
+	}
+	return params, nil
+}
+
+// DiffParameterGroups - what has to change in `to` for it to have the same user-modified
+// parameters as `from`, sorted by parameter name
+func (s *SDK) DiffParameterGroups(from ParameterGroupRef, to string) ([]ParameterDiff, error) {
+	src := from.SDK
+	if src == nil {
+		src = s
+	}
+	want, err := src.userParameters(from.Name)
+	if err != nil {
+		return nil, err
+	}
+	got, err := s.userParameters(to)
+	if err != nil {
+		return nil, err
+	}
+
+	diffs := []ParameterDiff{}
+	for name, w := range want {
+		g, ok := got[name]
+		if ok && aws.StringValue(g.ParameterValue) == aws.StringValue(w.ParameterValue) {
+			continue
+		}
+		d := ParameterDiff{Name: name, Value: w.ParameterValue, ApplyType: aws.StringValue(w.ApplyType)}
+		if ok {
+			d.Current = g.ParameterValue
+		}
+		diffs = append(diffs, d)
+	}
+	for name, g := range got {
+		if _, ok := want[name]; !ok {
+			diffs = append(diffs, ParameterDiff{Name: name, Current: g.ParameterValue, ApplyType: aws.StringValue(g.ApplyType)})
+		}
+	}
+
+	sort.Slice(diffs, func(a, b int) bool { return diffs[a].Name < diffs[b].Name })
+	return diffs, nil
+}
+
+// ApplyParameterDiff - set (or reset to the engine default) every parameter in `diffs`
+// on `group`, static ones only take effect once the instances using it are rebooted
+func (s *SDK) ApplyParameterDiff(group string, diffs []ParameterDiff) error {
+	set, reset := []*rds.Parameter{}, []*rds.Parameter{}
+	for _, d := range diffs {
+		s.log.Printf("... ApplyParameterDiff: [%24s] %s", group, d)
+		p := &rds.Parameter{
+			ParameterName: aws.String(d.Name),
+			ApplyMethod:   aws.String(d.applyMethod()),
+		}
+		if d.Value == nil {
+			reset = append(reset, p)
+			continue
+		}
+		p.ParameterValue = d.Value
+		set = append(set, p)
+	}
+
+	for len(set) > 0 {
+		n := len(set)
+		if n > maxParametersPerCall {
+			n = maxParametersPerCall
+		}
+		if _, err := s.svc.ModifyDBParameterGroupWithContext(s.ctx, &rds.ModifyDBParameterGroupInput{
+			DBParameterGroupName: aws.String(group),
+			Parameters:           set[:n],
+		}); err != nil {
+			return fmt.Errorf("ERROR: ModifyDBParameterGroupWithContext(%s) failed with: %v", group, err)
+		}
+		set = set[n:]
+	}
+
+	for len(reset) > 0 {
+		n := len(reset)
+		if n > maxParametersPerCall {
+			n = maxParametersPerCall
+		}
+		if _, err := s.svc.ResetDBParameterGroupWithContext(s.ctx, &rds.ResetDBParameterGroupInput{
+			DBParameterGroupName: aws.String(group),
+			Parameters:           reset[:n],
+		}); err != nil {
+			return fmt.Errorf("ERROR: ResetDBParameterGroupWithContext(%s) failed with: %v", group, err)
+		}
+		reset = reset[n:]
+	}
+	return nil
+}
+
+// clonedGroupName - what the clone of `group` is called, engine default groups can't
+// be created and have nothing to copy anyway, so they're shared as is
+func clonedGroupName(group string, np *NameParser) string {
+	if strings.HasPrefix(group, "default.") {
+		return group
+	}
+	return np.NewName(group)
+}
+
+// sameFamily - error unless the `existing` clone target is of the same family as `source`,
+// parameters can't be copied across families
+func sameFamily(fromName string, source, existing *rds.DBParameterGroup) error {
+	want, got := aws.StringValue(source.DBParameterGroupFamily), aws.StringValue(existing.DBParameterGroupFamily)
+	if got != want {
+		return fmt.Errorf("can't clone DB parameter group %q (%s) into %q (%s)", fromName, want, aws.StringValue(existing.DBParameterGroupName), got)
+	}
+	return nil
+}
+
+// CloneParameterGroup - make `to` equivalent to `from`: create it (same family and
+// description) if it doesn't exist yet and apply the diff, returns what was changed.
+// Safe to run concurrently for the same `to`, whoever loses the race to create it
+// just applies the diff
+func (s *SDK) CloneParameterGroup(from ParameterGroupRef, to string) ([]ParameterDiff, error) {
+	src := from.SDK
+	if src == nil {
+		src = s
+	}
+	out, err := src.svc.DescribeDBParameterGroupsWithContext(src.ctx, &rds.DescribeDBParameterGroupsInput{
+		DBParameterGroupName: aws.String(from.Name),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ERROR: DescribeDBParameterGroupsWithContext(%s) failed with: %v", from.Name, err)
+	}
+	if len(out.DBParameterGroups) == 0 {
+		return nil, fmt.Errorf("DB parameter group %q does not exist", from.Name)
+	}
+	source := out.DBParameterGroups[0]
+	description := aws.StringValue(source.Description)
+	if description == "" {
+		description = "clone of " + from.Name
+	}
+
+	existing, err := s.svc.DescribeDBParameterGroupsWithContext(s.ctx, &rds.DescribeDBParameterGroupsInput{
+		DBParameterGroupName: aws.String(to),
+	})
+	switch {
+	case err == nil && len(existing.DBParameterGroups) > 0:
+		if err := sameFamily(from.Name, source, existing.DBParameterGroups[0]); err != nil {
+			return nil, err
+		}
+		s.log.Printf("... CloneParameterGroup: [%24s] already exists", to)
+	case err == nil || AWSError(err, rds.ErrCodeDBParameterGroupNotFoundFault):
+		s.log.Printf("... CloneParameterGroup: [%24s] creating as a clone of %q", to, from.Name)
+		_, err := s.svc.CreateDBParameterGroupWithContext(s.ctx, &rds.CreateDBParameterGroupInput{
+			DBParameterGroupName:   aws.String(to),
+			DBParameterGroupFamily: source.DBParameterGroupFamily,
+			Description:            aws.String(description),
+		})
+		switch {
+		case AWSError(err, rds.ErrCodeDBParameterGroupAlreadyExistsFault):
+			// created since we looked (ie: another migration cloning the same group),
+			// the diff below brings it in line either way
+			existing, err := s.svc.DescribeDBParameterGroupsWithContext(s.ctx, &rds.DescribeDBParameterGroupsInput{
+				DBParameterGroupName: aws.String(to),
+			})
+			if err != nil {
+				return nil, fmt.Errorf("ERROR: DescribeDBParameterGroupsWithContext(%s) failed with: %v", to, err)
+			}
+			if len(existing.DBParameterGroups) == 0 {
+				return nil, fmt.Errorf("DB parameter group %q does not exist", to)
+			}
+			if err := sameFamily(from.Name, source, existing.DBParameterGroups[0]); err != nil {
+				return nil, err
+			}
+			s.log.Printf("... CloneParameterGroup: [%24s] already exists, created concurrently", to)
+		case err != nil:
+			return nil, fmt.Errorf("ERROR: CreateDBParameterGroupWithContext(%s) failed with: %v", to, err)
+		}
+	default:
+		return nil, fmt.Errorf("ERROR: DescribeDBParameterGroupsWithContext(%s) failed with: %v", to, err)
+	}
+
+	diffs, err := s.DiffParameterGroups(ParameterGroupRef{SDK: src, Name: from.Name}, to)
+	if err != nil {
+		return nil, err
+	}
+	if len(diffs) == 0 {
+		return diffs, nil
+	}
+	return diffs, s.ApplyParameterDiff(to, diffs)
+}
@@ -0,0 +1,50 @@
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+// racingRDS - fakeRDS where `group` is created by someone else right after the
+// first Describe says it doesn't exist
+type racingRDS struct {
+	*fakeRDS
+	group  string
+	family string
+	raced  bool
+}
+
+func (r *racingRDS) DescribeDBParameterGroupsWithContext(ctx aws.Context, in *rds.DescribeDBParameterGroupsInput, opts ...request.Option) (*rds.DescribeDBParameterGroupsOutput, error) {
+	out, err := r.fakeRDS.DescribeDBParameterGroupsWithContext(ctx, in, opts...)
+	if aws.StringValue(in.DBParameterGroupName) == r.group && !r.raced {
+		r.raced = true
+		r.AddParameterGroup(r.group, r.family)
+	}
+	return out, err
+}
+
+func TestCloneParameterGroupRace(t *testing.T) {
+	s, f := newFakeSDK(t)
+	family := fakeFamily("mysql", fakeEngineVersions["mysql"])
+	f.AddParameterGroup("prod-params", family)
+	f.SetParameter("prod-params", "max_connections", "500", "dynamic")
+	s.svc = &racingRDS{fakeRDS: f, group: "new-prod-params", family: family}
+
+	diffs, err := s.CloneParameterGroup(ParameterGroupRef{Name: "prod-params"}, "new-prod-params")
+	if err != nil {
+		t.Fatalf("CloneParameterGroup() = %v, want the concurrently created group to be used", err)
+	}
+	if len(diffs) != 1 || diffs[0].Name != "max_connections" {
+		t.Errorf("CloneParameterGroup() diffs = %+v, want max_connections", diffs)
+	}
+	if got := f.parameters["new-prod-params"]["max_connections"]; got == nil || aws.StringValue(got.ParameterValue) != "500" {
+		t.Errorf("max_connections of the clone = %v, want 500", got)
+	}
+
+	// a concurrently created group of another family is not taken over
+	s.svc = &racingRDS{fakeRDS: f, group: "new-other-params", family: "mysql5.6"}
+	if _, err := s.CloneParameterGroup(ParameterGroupRef{Name: "prod-params"}, "new-other-params"); err == nil {
+		t.Error("CloneParameterGroup() into a group of another family succeeded")
+	}
+}
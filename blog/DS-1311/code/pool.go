@@ -0,0 +1,68 @@
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MultiError - errors of work done in parallel, keyed by instance name
+type MultiError map[string]error
+
+func (e MultiError) Error() string {
+	names := []string{}
+	for name := range e {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	msgs := []string{}
+	for _, name := range names {
+		msgs = append(msgs, fmt.Sprintf("%s: %v", name, e[name]))
+	}
+	return fmt.Sprintf("%d instance(s) failed: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// forEach - call `fn` for every name with at most `limit` (< 1 means 1) of them running
+// at once, every name gets it's turn even if some fail, unless s.ctx is cancelled,
+// all of the errors are returned as a MultiError
+func (s *SDK) forEach(names []string, limit int, fn func(name string) error) error {
+	if limit < 1 {
+		limit = 1
+	}
+
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		errs = MultiError{}
+		sem  = make(chan struct{}, limit)
+	)
+	for _, name := range names {
+		select {
+		case sem <- struct{}{}:
+		case <-s.ctx.Done():
+			mu.Lock()
+			errs[name] = s.ctx.Err()
+			mu.Unlock()
+			continue
+		}
+
+		wg.Add(1)
+		go func(name string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := fn(name); err != nil {
+				mu.Lock()
+				errs[name] = err
+				mu.Unlock()
+			}
+		}(name)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -4,13 +4,28 @@ import (
 )
 
 func (s *SDK) reCreateReplica(master, copyFrom Instance, name string, binlogRetention int, rootPass string) error {
+	if err := s.createReplica(master, copyFrom, name); err != nil {
+		return err
+	}
+
+	return s.reCreateReplicaFinalize(master, copyFrom, name, binlogRetention, rootPass)
+}
+
+// createReplica - create `name` replica of `master` matching `copyFrom` attributes,
+// a no-op if the replica already exists
+func (s *SDK) createReplica(master, copyFrom Instance, name string) error {
 	for _, replica := range master.RDSDBInstance.ReadReplicaDBInstanceIdentifiers {
 		if *replica == name {
 			s.log.Printf("... reCreateReplica: [%24s] %q replica already exists", master.Name, name)
-			return s.reCreateReplicaFinalize(master, copyFrom, name, binlogRetention, rootPass)
+			return nil
 		}
 	}
 
+	_, optionGroupName, err := s.sourceGroups(copyFrom)
+	if err != nil {
+		return err
+	}
+
 	replicaInput := &rds.CreateDBInstanceReadReplicaInput{
 		AutoMinorVersionUpgrade: copyFrom.RDSDBInstance.AutoMinorVersionUpgrade,
 		// AvailabilityZone:            copyFrom.RDSDBInstance.AvailabilityZone,
@@ -26,7 +41,7 @@ func (s *SDK) reCreateReplica(master, copyFrom Instance, name string, binlogRete
 		MonitoringInterval:          copyFrom.RDSDBInstance.MonitoringInterval,
 		MonitoringRoleArn:           copyFrom.RDSDBInstance.MonitoringRoleArn,
 		MultiAZ:                     copyFrom.RDSDBInstance.MultiAZ,
-		OptionGroupName:             copyFrom.RDSDBInstance.OptionGroupMemberships[0].OptionGroupName,
+		OptionGroupName:             optionGroupName,
 		PerformanceInsightsKMSKeyId: copyFrom.RDSDBInstance.PerformanceInsightsKMSKeyId,
 		// Port:                        copyFrom.RDSDBInstance.DbInstancePort,
 		PubliclyAccessible:         copyFrom.RDSDBInstance.PubliclyAccessible,
@@ -41,34 +56,38 @@ func (s *SDK) reCreateReplica(master, copyFrom Instance, name string, binlogRete
 	}
 
 	s.log.Printf("... reCreateReplica: [%24s] creating %q replica based on %q", master.Name, name, copyFrom.Name)
-	if _, err := s.svc.CreateDBInstanceReadReplicaWithContext(s.ctx, replicaInput); err != nil {
-		return err
-	}
-
-	return s.reCreateReplicaFinalize(master, copyFrom, name, binlogRetention, rootPass)
+	_, err = s.svc.CreateDBInstanceReadReplicaWithContext(s.ctx, replicaInput)
+	return err
 }
 
 func (s *SDK) reCreateReplicaFinalize(master, copyFrom Instance, name string, binlogRetention int, rootPass string) error {
-	groupName := copyFrom.RDSDBInstance.DBParameterGroups[0].DBParameterGroupName
-	newReplica, err := s.ModifyInstance(name, *groupName, copyFrom.FilterVPCSecurityGroups(Active))
+	groupName, err := parameterGroup(copyFrom.RDSDBInstance)
 	if err != nil {
 		return err
 	}
-	return s.cloneReplica(master, copyFrom, newReplica, binlogRetention, rootPass)
+	newReplica, err := s.ModifyInstance(name, groupName, copyFrom.FilterVPCSecurityGroups(Active))
+	if err != nil {
+		return err
+	}
+	return s.cloneReplica(master, copyFrom, newReplica, binlogRetention, rootPass, UserCloneOptions{})
 }
 
-func (s *SDK) cloneReplica(master, copyFrom, newReplica Instance, binlogRetention int, rootPass string) error {
+func (s *SDK) cloneReplica(master, copyFrom, newReplica Instance, binlogRetention int, rootPass string, users UserCloneOptions) error {
 	// for now only support mysql
 	if newReplica.Engine != "mysql" {
 		return nil
 	}
 
 	rc := &mysqlReplicaClone{
-		copyFrom:     copyFrom,
-		copyTo:       newReplica,
-		rootPass:     rootPass,
-		binlogRetHrs: aws.Int(binlogRetention),
-		log:          s.log,
+		copyFrom: copyFrom,
+		copyTo:   newReplica,
+		rootPass: rootPass,
+		users:    users,
+		log:      s.log,
+	}
+	// binlog retention is copied from copyFrom (with the rest of the RDS configuration) unless overridden
+	if binlogRetention > 0 {
+		rc.binlogRetHrs = aws.Int(binlogRetention)
 	}
 	dryRun := false
 	return rc.execute(s.Verbose, dryRun)
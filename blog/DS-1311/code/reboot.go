@@ -0,0 +1,124 @@
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+)
+
+// Multi-AZ failover event messages (prefixes), see eventRestarted
+const (
+	eventFailoverStarted   = "Multi-AZ instance failover started"
+	eventFailoverCompleted = "Multi-AZ instance failover completed"
+)
+
+// RebootOptions - how to bounce an instance
+type RebootOptions struct {
+	// Failover - for Multi-AZ instances reboot with failover to the standby, which
+	// keeps the unavailable window down to the DNS flip, Single-AZ instances are
+	// rebooted the usual way
+	Failover bool
+
+	// Timeout - how long to wait for the instance to be available again, 0 means defaultWaitTimeout
+	Timeout time.Duration
+}
+
+// RebootResult - what RebootInstance did and how long the instance was unavailable for
+type RebootResult struct {
+	Name        string
+	Failover    bool
+	FromAZ      string
+	ToAZ        string
+	Unavailable time.Duration
+	Events      []EventRecord
+}
+
+func (r *RebootResult) String() string {
+	if r.Failover {
+		return fmt.Sprintf("failed over from %s to %s, unavailable for %s", r.FromAZ, r.ToAZ, r.Unavailable.Round(time.Second))
+	}
+	return fmt.Sprintf("rebooted in %s, unavailable for %s", r.ToAZ, r.Unavailable.Round(time.Second))
+}
+
+// RebootInstance - reboot `name` and wait for it to become available again. With
+// opts.Failover a Multi-AZ instance is checked to have a standby in a different AZ
+// before, and to have moved to it after, the reboot. The unavailable window is taken
+// from the failover (or restart) events when RDS reports both ends of it, otherwise
+// it's the time from the reboot request until the instance was available again.
+func (s *SDK) RebootInstance(name string, opts RebootOptions) (*RebootResult, error) {
+	i, err := s.Describe(name)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &RebootResult{
+		Name:   name,
+		FromAZ: aws.StringValue(i.RDSDBInstance.AvailabilityZone),
+	}
+	secondary := aws.StringValue(i.RDSDBInstance.SecondaryAvailabilityZone)
+	if opts.Failover {
+		if !aws.BoolValue(i.RDSDBInstance.MultiAZ) {
+			s.log.Printf("... RebootInstance: [%24s] not Multi-AZ, rebooting without failover", name)
+		} else {
+			if i.Status != Available {
+				return nil, fmt.Errorf("can't failover %q, status is %q", name, i.Status)
+			}
+			if secondary == "" || secondary == r.FromAZ {
+				return nil, fmt.Errorf("can't failover %q, no standby in a different AZ (primary: %q, secondary: %q)", name, r.FromAZ, secondary)
+			}
+			r.Failover = true
+		}
+	}
+
+	since := time.Now()
+	if r.Failover {
+		s.log.Printf("... RebootInstance: [%24s] Rebooting with failover from %s to %s", name, r.FromAZ, secondary)
+	} else {
+		s.log.Printf("... RebootInstance: [%24s] Rebooting", name)
+	}
+	if err := s.Reboot(name, r.Failover); err != nil {
+		return nil, err
+	}
+
+	timeline := &EventTimeline{}
+	want := []string{eventRestarted}
+	if r.Failover {
+		want = []string{eventFailoverCompleted}
+	}
+	if err := s.waitForDBEvent(name, since, opts.Timeout, want, s.availableFunc("RebootInstance"), timeline); err != nil {
+		return nil, err
+	}
+	r.Unavailable = time.Since(since)
+	r.Events = timeline.Events()
+	if d, ok := eventWindow(r.Events, eventFailoverStarted, eventFailoverCompleted); ok {
+		r.Unavailable = d
+	}
+
+	if i, err = s.Describe(name); err != nil {
+		return nil, err
+	}
+	r.ToAZ = aws.StringValue(i.RDSDBInstance.AvailabilityZone)
+	if r.Failover && r.ToAZ != secondary {
+		return r, fmt.Errorf("failover of %q ended up in %q, expected the standby AZ %q", name, r.ToAZ, secondary)
+	}
+
+	s.log.Printf("... RebootInstance: [%24s] %s", name, r)
+	return r, nil
+}
+
+// eventWindow - time between the first `start` and the last `end` event
+func eventWindow(events []EventRecord, start, end string) (time.Duration, bool) {
+	var from, to time.Time
+	for _, e := range events {
+		if strings.HasPrefix(e.Message, start) && from.IsZero() {
+			from = e.Time
+		}
+		if strings.HasPrefix(e.Message, end) {
+			to = e.Time
+		}
+	}
+	if from.IsZero() || to.IsZero() || to.Before(from) {
+		return 0, false
+	}
+	return to.Sub(from), true
+}
@@ -0,0 +1,41 @@
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+func TestRebootInstanceFailover(t *testing.T) {
+	s, f := newFakeSDK(t)
+	addFakeMaster(f, "mysql", "prod-one")
+
+	before, err := s.Describe("prod-one")
+	if err != nil {
+		t.Fatal(err)
+	}
+	standby := aws.StringValue(before.RDSDBInstance.SecondaryAvailabilityZone)
+	if standby == "" || standby == aws.StringValue(before.RDSDBInstance.AvailabilityZone) {
+		t.Fatalf("Multi-AZ fake has no standby in a different AZ: %q", standby)
+	}
+
+	r, err := s.RebootInstance("prod-one", RebootOptions{Failover: true, Timeout: testWaitTimeout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.Failover || r.ToAZ != standby || r.FromAZ == r.ToAZ {
+		t.Errorf("RebootInstance() = %s, want a failover to %s", r, standby)
+	}
+}
+
+func TestRebootInstanceSingleAZ(t *testing.T) {
+	s, f := newFakeSDK(t)
+	f.AddInstance(&rds.DBInstance{DBInstanceIdentifier: aws.String("prod-one")})
+
+	r, err := s.RebootInstance("prod-one", RebootOptions{Failover: true, Timeout: testWaitTimeout})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Failover || r.ToAZ != r.FromAZ {
+		t.Errorf("RebootInstance() = %s, want a plain reboot", r)
+	}
+}
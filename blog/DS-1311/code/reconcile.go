@@ -0,0 +1,286 @@
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+// DesiredState - what an instance should look like, zero values are left alone
+type DesiredState struct {
+	DBParameterGroupName string
+	VpcSecurityGroupIds  []*string
+	OptionGroupName      string
+	DBInstanceClass      string
+	MultiAZ              *bool
+
+	// Source - if set, every attribute in restorableAttrs is matched with it
+	Source *rds.DBInstance
+
+	// CloneParameterGroupFrom - if set, DBParameterGroupName is first made equivalent
+	// to this group with CloneParameterGroup (and created if it doesn't exist)
+	CloneParameterGroupFrom *ParameterGroupRef
+
+	// Reboot - how to bounce the instance if the parameter group change needs it
+	Reboot RebootOptions
+
+	// WaitTimeout - for the instance to be available and then for the modification
+	// to be applied, 0 means defaultWaitTimeout
+	WaitTimeout time.Duration
+
+	// Timeline - if set, collects the RDS events of the parameter group change and
+	// the reboot, ie: for the migration report
+	Timeline *EventTimeline
+}
+
+// DesiredStateFrom - everything ModifyDBInstance can bring in line with `src`
+func DesiredStateFrom(src Instance) *DesiredState {
+	return &DesiredState{
+		DBParameterGroupName: parGroupName(src.RDSDBInstance),
+		VpcSecurityGroupIds:  src.FilterVPCSecurityGroups(Active),
+		OptionGroupName:      optionGroupName(src.RDSDBInstance),
+		DBInstanceClass:      aws.StringValue(src.RDSDBInstance.DBInstanceClass),
+		MultiAZ:              src.RDSDBInstance.MultiAZ,
+		Source:               src.RDSDBInstance,
+	}
+}
+
+// NotConvergedError - fields that still differ from the desired state after reconciling
+type NotConvergedError struct {
+	Name   string
+	Fields []string
+}
+
+func (e *NotConvergedError) Error() string {
+	return fmt.Sprintf("%q did not converge, still different: %s", e.Name, strings.Join(e.Fields, commaSep))
+}
+
+// diff - names of the fields of `db` that differ from `want`, and the minimal
+// ModifyDBInstance request to fix them (nil if none of them can be fixed)
+func (want *DesiredState) diff(db *rds.DBInstance) ([]string, *rds.ModifyDBInstanceInput) {
+	input := &rds.ModifyDBInstanceInput{
+		DBInstanceIdentifier: db.DBInstanceIdentifier,
+		ApplyImmediately:     aws.Bool(true),
+	}
+	fields := []string{}
+	unsettable := 0
+
+	if want.DBParameterGroupName != "" && want.DBParameterGroupName != parGroupName(db) {
+		fields = append(fields, "DBParameterGroupName")
+		input.DBParameterGroupName = aws.String(want.DBParameterGroupName)
+	}
+	if len(want.VpcSecurityGroupIds) > 0 && !sameStrings(want.VpcSecurityGroupIds, activeVpcSecurityGroups(db)) {
+		fields = append(fields, "VpcSecurityGroupIds")
+		input.VpcSecurityGroupIds = want.VpcSecurityGroupIds
+	}
+	if want.OptionGroupName != "" && want.OptionGroupName != optionGroupName(db) {
+		fields = append(fields, "OptionGroupName")
+		input.OptionGroupName = aws.String(want.OptionGroupName)
+	}
+	if want.DBInstanceClass != "" && want.DBInstanceClass != aws.StringValue(db.DBInstanceClass) {
+		fields = append(fields, "DBInstanceClass")
+		input.DBInstanceClass = aws.String(want.DBInstanceClass)
+	}
+	if want.MultiAZ != nil && *want.MultiAZ != aws.BoolValue(db.MultiAZ) {
+		fields = append(fields, "MultiAZ")
+		input.MultiAZ = want.MultiAZ
+	}
+	if want.Source != nil {
+		for _, a := range restorableAttrs {
+			if a.get(want.Source) != a.get(db) {
+				fields = append(fields, a.name)
+				if !a.set(want.Source, input) {
+					unsettable++
+				}
+			}
+		}
+	}
+
+	if len(fields) == unsettable {
+		return fields, nil
+	}
+	return fields, input
+}
+
+// Reconcile - bring `name` to the `want` state: diff it against Describe, issue a single
+// minimal ModifyDBInstance, reboot if the parameter group change needs it and wait for
+// every field to converge (NotConvergedError if it doesn't within want.WaitTimeout)
+func (s *SDK) Reconcile(name string, want *DesiredState) (Instance, error) {
+	i, _, err := s.reconcile(name, want)
+	return i, err
+}
+
+// reconcile - Reconcile, also returning the reboot (nil if there wasn't one)
+func (s *SDK) reconcile(name string, want *DesiredState) (Instance, *RebootResult, error) {
+	if err := s.waitForDBStatusWithin(name, want.WaitTimeout, s.availableFunc("Reconcile")); err != nil {
+		return Instance{}, nil, err
+	}
+
+	i, err := s.Describe(name)
+	if err != nil {
+		return Instance{}, nil, err
+	}
+
+	if want.CloneParameterGroupFrom != nil && want.DBParameterGroupName != "" {
+		if _, err := s.CloneParameterGroup(*want.CloneParameterGroupFrom, want.DBParameterGroupName); err != nil {
+			return Instance{}, nil, err
+		}
+	}
+
+	timeline := want.Timeline
+	if timeline == nil {
+		timeline = &EventTimeline{}
+	}
+
+	fields, input := want.diff(i.RDSDBInstance)
+	var since time.Time
+	if input != nil && input.DBParameterGroupName != nil {
+		if err := s.validateParameterGroup(*input.DBParameterGroupName, i.RDSDBInstance); err != nil {
+			return Instance{}, nil, err
+		}
+	}
+	if input != nil && input.OptionGroupName != nil {
+		if err := s.validateOptionGroup(*input.OptionGroupName, i.RDSDBInstance); err != nil {
+			return Instance{}, nil, err
+		}
+	}
+	if input != nil {
+		s.log.Printf("... Reconcile: [%24s] modifying: %s", name, strings.Join(fields, commaSep))
+		since = time.Now()
+		if _, err := s.svc.ModifyDBInstanceWithContext(s.ctx, input); err != nil {
+			return Instance{}, nil, fmt.Errorf("ERROR: ModifyDBInstanceWithContext(%s) failed with: %v", name, err)
+		}
+
+		if err := s.waitForDBStatusWithin(name, want.WaitTimeout, s.availableFunc("Reconcile")); err != nil {
+			return Instance{}, nil, err
+		}
+	} else if len(fields) > 0 {
+		s.log.Printf("... Reconcile: [%24s] can't modify: %s", name, strings.Join(fields, commaSep))
+	} else {
+		s.log.Printf("... Reconcile: [%24s] nothing to modify", name)
+	}
+
+	if input != nil && input.DBParameterGroupName != nil {
+		// DB instance has to be bounced now, but we need to wait until
+		// parameter group status == pending-reboot before doing so ...
+		readyFunc := func(i Instance) bool {
+			ok := i.ParGroupStatus == pendingReboot
+			if !ok {
+				s.log.Printf("... Reconcile: [%24s] waiting for %s group status change, want: %s got: %s", name, want.DBParameterGroupName, pendingReboot, i.ParGroupStatus)
+			}
+			return ok
+		}
+		if err := s.waitForDBEvent(name, since, want.WaitTimeout, []string{eventParamsApplied}, readyFunc, timeline); err != nil {
+			return Instance{}, nil, err
+		}
+	}
+
+	if i, err = s.Describe(name); err != nil {
+		return Instance{}, nil, err
+	}
+	var rebooted *RebootResult
+	// also covers a parameter group change from a previous (interrupted) run
+	if i.ParGroupStatus == pendingReboot {
+		s.log.Printf("... Reconcile: [%24s] Rebooting, for DBParameterGroupName %q to take effect", name, parGroupName(i.RDSDBInstance))
+		rebooted, err = s.RebootInstance(name, want.Reboot)
+		if rebooted != nil {
+			for _, e := range rebooted.Events {
+				timeline.record(e)
+			}
+		}
+		if err != nil {
+			return Instance{}, rebooted, err
+		}
+		if i, err = s.Describe(name); err != nil {
+			return Instance{}, nil, err
+		}
+	}
+
+	// class, Multi-AZ and storage changes often haven't even started by now, they sit
+	// in PendingModifiedValues while the instance is still available
+	convergedFunc := func(i Instance) bool {
+		if pending := pendingModifications(i.RDSDBInstance); len(pending) > 0 {
+			s.log.Printf("... Reconcile: [%24s] waiting for pending modifications: %s", name, strings.Join(pending, commaSep))
+			return false
+		}
+		if fields, input := want.diff(i.RDSDBInstance); input != nil {
+			s.log.Printf("... Reconcile: [%24s] waiting for %s to converge", name, strings.Join(fields, commaSep))
+			return false
+		}
+		return i.Status == Available
+	}
+	var te *WaitTimeoutError
+	if err := s.waitForDBStatusWithin(name, want.WaitTimeout, convergedFunc); err != nil && !errors.As(err, &te) {
+		return Instance{}, rebooted, err
+	}
+
+	if i, err = s.Describe(name); err != nil {
+		return Instance{}, rebooted, err
+	}
+	if fields, _ := want.diff(i.RDSDBInstance); len(fields) > 0 {
+		return i, rebooted, &NotConvergedError{Name: name, Fields: fields}
+	}
+	return i, rebooted, nil
+}
+
+// pendingModifications - fields of PendingModifiedValues that are set, ie: a class
+// change RDS accepted but hasn't applied yet
+func pendingModifications(db *rds.DBInstance) []string {
+	fields := []string{}
+	if db.PendingModifiedValues == nil {
+		return fields
+	}
+	v := reflect.ValueOf(db.PendingModifiedValues).Elem()
+	for n := 0; n < v.NumField(); n++ {
+		f := v.Field(n)
+		switch {
+		case v.Type().Field(n).PkgPath != "":
+			continue // unexported
+		case f.Kind() == reflect.Ptr && !f.IsNil(), f.Kind() == reflect.Slice && f.Len() > 0:
+			fields = append(fields, v.Type().Field(n).Name)
+		}
+	}
+	return fields
+}
+
+// parGroupName - parameterGroup, "" if there isn't exactly one
+func parGroupName(db *rds.DBInstance) string {
+	name, _ := parameterGroup(db)
+	return name
+}
+
+// optionGroupName - optionGroup, "" if there isn't exactly one
+func optionGroupName(db *rds.DBInstance) string {
+	name, _ := optionGroup(db)
+	return aws.StringValue(name)
+}
+
+func activeVpcSecurityGroups(db *rds.DBInstance) []*string {
+	ids := []*string{}
+	for _, sg := range db.VpcSecurityGroups {
+		if aws.StringValue(sg.Status) == Active {
+			ids = append(ids, sg.VpcSecurityGroupId)
+		}
+	}
+	return ids
+}
+
+// sameStrings - same set of values, regardless of order
+func sameStrings(a, b []*string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	as, bs := aws.StringValueSlice(a), aws.StringValueSlice(b)
+	sort.Strings(as)
+	sort.Strings(bs)
+	for n := range as {
+		if as[n] != bs[n] {
+			return false
+		}
+	}
+	return true
+}
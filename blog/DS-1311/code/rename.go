@@ -0,0 +1,182 @@
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+// lookupHost - net.LookupHost, swapped out in tests (the fake's endpoints don't resolve)
+var lookupHost = net.LookupHost
+
+type renamePair struct {
+	from string
+	to   string
+}
+
+// RenameInstance - rename `from` to `to`, wait (up to `timeout`, defaultWaitTimeout if 0)
+// for it to become available under the new name and verify it's endpoint address changed
+// to match the new name
+func (s *SDK) RenameInstance(from, to string, timeout time.Duration) (Instance, error) {
+	if err := s.renameAll([]renamePair{{from: from, to: to}}, timeout); err != nil {
+		return Instance{}, err
+	}
+	return s.Describe(to)
+}
+
+// SwapInstances - replace `name` (and all of it's replicas) with their encrypted copies:
+// renames prod-x to old-prod-x and then new-old-prod-x to prod-x, this is what the
+// six hand-typed `aws rds modify-db-instance --new-db-instance-identifier` used to do.
+// Each rename has to complete within `timeout` (defaultWaitTimeout if 0)
+func (s *SDK) SwapInstances(name string, np *NameParser, timeout time.Duration) error {
+	master, err := s.Describe(name)
+	if err != nil {
+		return err
+	}
+
+	names := []string{name}
+	for _, replica := range master.RDSDBInstance.ReadReplicaDBInstanceIdentifiers {
+		names = append(names, *replica)
+	}
+
+	// make sure all of the replacements exist before touching anything
+	out, in := []renamePair{}, []renamePair{}
+	for _, n := range names {
+		old := oldPrefix + n
+		newName := np.NewName(old)
+		if _, err := s.Describe(newName); err != nil {
+			return fmt.Errorf("ERROR: SwapInstances(%s) can't find replacement %q for %q: %v", name, newName, n, err)
+		}
+		out = append(out, renamePair{from: n, to: old})
+		in = append(in, renamePair{from: newName, to: n})
+	}
+
+	s.log.Printf("... SwapInstances: [%24s] renaming %d instance(s) to %s*", name, len(out), oldPrefix)
+	if err := s.renameAll(out, timeout); err != nil {
+		return err
+	}
+
+	s.log.Printf("... SwapInstances: [%24s] renaming %d replacement(s) to their original names", name, len(in))
+	return s.renameAll(in, timeout)
+}
+
+// renameAll - kick off all renames at once (there is no need to wait for the
+// master rename to complete before renaming it's replicas) and then wait
+// for all of them to finish, each within `timeout` (defaultWaitTimeout if 0)
+func (s *SDK) renameAll(pairs []renamePair, timeout time.Duration) error {
+	oldAddrs := make(map[string]string)
+	for _, p := range pairs {
+		addr, err := s.startRename(p.from, p.to)
+		if err != nil {
+			return err
+		}
+		oldAddrs[p.to] = addr
+	}
+
+	for _, p := range pairs {
+		if err := s.waitForRename(p.from, p.to, oldAddrs[p.to], timeout); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startRename - issue the rename and return the endpoint address `from` had before it,
+// a no-op (returning an empty address) if `from` is already renamed to `to`
+func (s *SDK) startRename(from, to string) (string, error) {
+	_, err := s.Describe(to)
+	if err == nil {
+		if _, err := s.Describe(from); err == nil {
+			return "", fmt.Errorf("ERROR: can't rename %q to %q, both already exist", from, to)
+		}
+		s.log.Printf("... RenameInstance: [%24s] already renamed to %q", from, to)
+		return "", nil
+	}
+	if !AWSError(err, rds.ErrCodeDBInstanceNotFoundFault) {
+		return "", err
+	}
+
+	i, err := s.Describe(from)
+	if err != nil {
+		return "", err
+	}
+
+	s.log.Printf("... RenameInstance: [%24s] renaming to %q", from, to)
+	if _, err := s.svc.ModifyDBInstanceWithContext(s.ctx, &rds.ModifyDBInstanceInput{
+		DBInstanceIdentifier:    aws.String(from),
+		NewDBInstanceIdentifier: aws.String(to),
+		ApplyImmediately:        aws.Bool(true),
+	}); err != nil {
+		return "", fmt.Errorf("ERROR: ModifyDBInstanceWithContext(%s) rename to %q failed with: %v", from, to, err)
+	}
+
+	return endpointAddress(i), nil
+}
+
+// waitForRename - wait until `to` is available and it's endpoint resolves
+// to an address matching the new name and different from `oldAddr`
+func (s *SDK) waitForRename(from, to, oldAddr string, timeout time.Duration) error {
+	if timeout == 0 {
+		timeout = defaultWaitTimeout
+	}
+	deadline := time.Now().Add(timeout)
+
+	// the new name shows up only once the rename is well underway,
+	// until then Describe returns DBInstanceNotFound for it
+	err := poll(s.ctx, timeout, func() (bool, error) {
+		_, err := s.Describe(to)
+		if err == nil {
+			return true, nil
+		}
+		if !AWSError(err, rds.ErrCodeDBInstanceNotFoundFault) {
+			return false, err
+		}
+		s.log.Printf("... RenameInstance: [%24s] waiting for %q to show up", from, to)
+		return false, nil
+	})
+	if err != nil {
+		return timedOut(err, to, "", "")
+	}
+
+	readyFunc := func(i Instance) bool {
+		if i.Status != Available {
+			s.log.Printf("... RenameInstance: [%24s] waiting for DB status to change from %q to %q", to, i.Status, Available)
+			return false
+		}
+
+		addr := endpointAddress(i)
+		if !strings.HasPrefix(addr, to+".") {
+			s.log.Printf("... RenameInstance: [%24s] waiting for endpoint to change, got: %q", to, addr)
+			return false
+		}
+
+		if _, err := lookupHost(addr); err != nil {
+			s.log.Printf("... RenameInstance: [%24s] waiting for endpoint %q to resolve: %v", to, addr, err)
+			return false
+		}
+		return true
+	}
+	if err := s.waitForDBStatusWithin(to, time.Until(deadline), readyFunc); err != nil {
+		return err
+	}
+
+	i, err := s.Describe(to)
+	if err != nil {
+		return err
+	}
+	if addr := endpointAddress(i); addr == oldAddr {
+		return fmt.Errorf("ERROR: %q endpoint address did not change after rename from %q: %q", to, from, addr)
+	}
+
+	s.log.Printf("... RenameInstance: [%24s] renamed from %q, endpoint: %q", to, from, endpointAddress(i))
+	return nil
+}
+
+func endpointAddress(i Instance) string {
+	if i.RDSDBInstance == nil || i.RDSDBInstance.Endpoint == nil {
+		return ""
+	}
+	return aws.StringValue(i.RDSDBInstance.Endpoint.Address)
+}
@@ -0,0 +1,44 @@
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+func TestSwapInstances(t *testing.T) {
+	s, f := newFakeSDK(t)
+	np := &NameParser{}
+	addFakeMaster(f, "mysql", "prod-one")
+	replacement := np.NewName(oldPrefix + "prod-one")
+	f.AddInstance(&rds.DBInstance{DBInstanceIdentifier: aws.String(replacement), StorageEncrypted: aws.Bool(true)})
+
+	if err := s.SwapInstances("prod-one", np, testWaitTimeout); err != nil {
+		t.Fatal(err)
+	}
+
+	i, err := s.Describe("prod-one")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !aws.BoolValue(i.RDSDBInstance.StorageEncrypted) {
+		t.Errorf("%q is still the unencrypted instance", i.Name)
+	}
+	if _, err := s.Describe(oldPrefix + "prod-one"); err != nil {
+		t.Errorf("original instance wasn't renamed to %s: %v", oldPrefix, err)
+	}
+	if _, err := s.Describe(replacement); !AWSError(err, rds.ErrCodeDBInstanceNotFoundFault) {
+		t.Errorf("Describe(%s) = %v, want %s", replacement, err, rds.ErrCodeDBInstanceNotFoundFault)
+	}
+}
+
+func TestSwapInstancesMissingReplacement(t *testing.T) {
+	s, f := newFakeSDK(t)
+	addFakeMaster(f, "mysql", "prod-one")
+
+	if err := s.SwapInstances("prod-one", &NameParser{}, testWaitTimeout); err == nil {
+		t.Fatal("SwapInstances() without a replacement succeeded")
+	}
+	if _, err := s.Describe("prod-one"); err != nil {
+		t.Errorf("prod-one was touched: %v", err)
+	}
+}
@@ -7,23 +7,15 @@ import (
 )
 
 // RestoreInstance - restore RDS snapshot for `sorceInstance` and match all of it's configurations
-// waits for DBParameterGroupName to take effect before returning to caller (does a reboot as a final step)
+// waits for DBParameterGroupName to take effect before returning to caller (does a reboot as a final step),
+// attributes that could not be matched are returned as a *NotConvergedError along with the restored instance
 func (s *SDK) RestoreInstance(sorceInstance Instance, takeFreshSnap bool, kmsKeyID string, np *NameParser) (Instance, error) {
-	targetName := np.NewName(sorceInstance.Name)
-	dbParGroupName := sorceInstance.RDSDBInstance.DBParameterGroups[0].DBParameterGroupName
-	vpcSecurityGroups := sorceInstance.FilterVPCSecurityGroups(Active)
-
-	// check if the targetName already exists and if so return it
-	i, err := s.Describe(targetName)
+	t, err := s.restoreTargetFor("RestoreInstance", sorceInstance, np)
 	if err != nil {
-		if !AWSError(err, rds.ErrCodeDBInstanceNotFoundFault) {
-			return Instance{}, err
-		}
+		return Instance{}, err
 	}
-
-	if i.Name == targetName {
-		s.log.Printf("... RestoreInstance: [%24s] target name %q already exists with status %q", sorceInstance.Name, targetName, i.Status)
-		return s.ModifyInstance(targetName, *dbParGroupName, vpcSecurityGroups)
+	if t.exists() {
+		return s.matchTarget(t)
 	}
 
 	snap, err := s.GenerateSnapshot(
@@ -37,29 +29,111 @@ func (s *SDK) RestoreInstance(sorceInstance Instance, takeFreshSnap bool, kmsKey
 		spew.Dump(snap)
 	}
 
-	s.log.Printf("... RestoreInstance: [%24s] Restoring from %q to %q", sorceInstance.Name, *snap.DBSnapshotIdentifier, targetName)
-	snapInput := &rds.RestoreDBInstanceFromDBSnapshotInput{
-		AutoMinorVersionUpgrade: sorceInstance.RDSDBInstance.AutoMinorVersionUpgrade,
-		// AvailabilityZone:            sorceInstance.RDSDBInstance.AvailabilityZone,
-		CopyTagsToSnapshot:          sorceInstance.RDSDBInstance.CopyTagsToSnapshot,
-		DBInstanceClass:             sorceInstance.RDSDBInstance.DBInstanceClass,
-		DBInstanceIdentifier:        aws.String(targetName),
-		DBSnapshotIdentifier:        snap.DBSnapshotIdentifier,
-		DBSubnetGroupName:           sorceInstance.RDSDBInstance.DBSubnetGroup.DBSubnetGroupName,
-		Iops:                        sorceInstance.RDSDBInstance.Iops,
-		MultiAZ:                     sorceInstance.RDSDBInstance.MultiAZ,
-		EnableCloudwatchLogsExports: sorceInstance.RDSDBInstance.EnabledCloudwatchLogsExports,
-		OptionGroupName:             sorceInstance.RDSDBInstance.OptionGroupMemberships[0].OptionGroupName,
-		PubliclyAccessible:          sorceInstance.RDSDBInstance.PubliclyAccessible,
-		StorageType:                 sorceInstance.RDSDBInstance.StorageType,
-		Tags:                        sorceInstance.TagList,
+	return s.restoreFromSnapshot(t, snap)
+}
+
+// RestoreInstanceFromSnapshot - same as RestoreInstance, but from an existing `snap`
+// (ie: one managed by SnapshotManager) instead of calling GenerateSnapshot
+func (s *SDK) RestoreInstanceFromSnapshot(sorceInstance Instance, snap *rds.DBSnapshot, np *NameParser) (Instance, error) {
+	return s.restoreInstanceFromSnapshot(sorceInstance, snap, np, nil)
+}
+
+// restoreInstanceFromSnapshot - RestoreInstanceFromSnapshot, collecting the events of
+// the post-restore parameter group change and reboot in `timeline` (if not nil)
+func (s *SDK) restoreInstanceFromSnapshot(sorceInstance Instance, snap *rds.DBSnapshot, np *NameParser, timeline *EventTimeline) (Instance, error) {
+	t, err := s.restoreTargetFor("RestoreInstance", sorceInstance, np)
+	if err != nil {
+		return Instance{}, err
 	}
-	if !*sorceInstance.RDSDBInstance.MultiAZ {
-		snapInput.AvailabilityZone = sorceInstance.RDSDBInstance.AvailabilityZone
+	t.timeline = timeline
+	if t.exists() {
+		return s.matchTarget(t)
 	}
+	return s.restoreFromSnapshot(t, snap)
+}
+
+// restoreFromSnapshot - the restore itself, `t` doesn't exist yet
+func (s *SDK) restoreFromSnapshot(t *restoreTarget, snap *rds.DBSnapshot) (Instance, error) {
+	s.log.Printf("... RestoreInstance: [%24s] Restoring from %q to %q", t.source.Name, *snap.DBSnapshotIdentifier, t.name)
+	snapInput := t.input()
+	snapInput.DBInstanceIdentifier = aws.String(t.name)
+	snapInput.DBSnapshotIdentifier = snap.DBSnapshotIdentifier
 	if _, err := s.svc.RestoreDBInstanceFromDBSnapshotWithContext(s.ctx, snapInput); err != nil {
 		return Instance{}, fmt.Errorf("ERROR: RestoreDBInstanceFromDBSnapshotWithContext(%s) failed with: %v", *snap.DBSnapshotIdentifier, err)
 	}
 
-	return s.ModifyInstance(targetName, *dbParGroupName, vpcSecurityGroups)
+	return s.matchTarget(t)
+}
+
+// restoreTarget - what every restore of `source` needs, worked out once per restore
+type restoreTarget struct {
+	source            Instance
+	name              string
+	dbParGroupName    string
+	optionGroupName   *string
+	vpcSecurityGroups []*string
+	existing          Instance       // zero value if the target doesn't exist yet
+	timeline          *EventTimeline // see DesiredState.Timeline
+}
+
+// restoreTargetFor - groups of `sorceInstance` (see sourceGroups) and whether the target
+// already exists, ie: the restore is re-run after a failure
+func (s *SDK) restoreTargetFor(fn string, sorceInstance Instance, np *NameParser) (*restoreTarget, error) {
+	dbParGroupName, optionGroupName, err := s.sourceGroups(sorceInstance)
+	if err != nil {
+		return nil, err
+	}
+	t := &restoreTarget{
+		source:            sorceInstance,
+		name:              np.NewName(sorceInstance.Name),
+		dbParGroupName:    dbParGroupName,
+		optionGroupName:   optionGroupName,
+		vpcSecurityGroups: sorceInstance.FilterVPCSecurityGroups(Active),
+	}
+
+	i, err := s.Describe(t.name)
+	if err != nil {
+		if !AWSError(err, rds.ErrCodeDBInstanceNotFoundFault) {
+			return nil, err
+		}
+	}
+	if i.Name == t.name {
+		s.log.Printf("... %s: [%24s] target name %q already exists with status %q", fn, sorceInstance.Name, t.name, i.Status)
+		t.existing = i
+	}
+	return t, nil
+}
+
+func (t *restoreTarget) exists() bool {
+	return t.existing.Name != ""
+}
+
+// input - the attributes taken from the source that both RestoreDBInstanceFromDBSnapshot
+// and RestoreDBInstanceToPointInTime accept (the latter gets them with awsutil.Copy),
+// callers fill in the identifiers
+func (t *restoreTarget) input() *rds.RestoreDBInstanceFromDBSnapshotInput {
+	src := t.source
+	input := &rds.RestoreDBInstanceFromDBSnapshotInput{
+		AutoMinorVersionUpgrade: src.RDSDBInstance.AutoMinorVersionUpgrade,
+		// AvailabilityZone:            src.RDSDBInstance.AvailabilityZone,
+		CopyTagsToSnapshot:          src.RDSDBInstance.CopyTagsToSnapshot,
+		DBInstanceClass:             src.RDSDBInstance.DBInstanceClass,
+		DBSubnetGroupName:           src.RDSDBInstance.DBSubnetGroup.DBSubnetGroupName,
+		Iops:                        src.RDSDBInstance.Iops,
+		MultiAZ:                     src.RDSDBInstance.MultiAZ,
+		EnableCloudwatchLogsExports: src.RDSDBInstance.EnabledCloudwatchLogsExports,
+		OptionGroupName:             t.optionGroupName,
+		PubliclyAccessible:          src.RDSDBInstance.PubliclyAccessible,
+		StorageType:                 src.RDSDBInstance.StorageType,
+		Tags:                        src.TagList,
+	}
+	if !*src.RDSDBInstance.MultiAZ {
+		input.AvailabilityZone = src.RDSDBInstance.AvailabilityZone
+	}
+	return input
+}
+
+// matchTarget - modifyAndMatch `t` with it's source
+func (s *SDK) matchTarget(t *restoreTarget) (Instance, error) {
+	return s.modifyAndMatch(t.source, t.name, t.dbParGroupName, t.vpcSecurityGroups, t.timeline)
 }
@@ -0,0 +1,133 @@
+import (
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+// Rollback - undo a migration recorded in opts.StateFile: move the encrypted instances
+// aside (back to their new-old-* names), give the original old-* instances their names
+// back, reboot them and verify endpoints and replica topology match what they were
+// before the migration started, `names` defaults to every instance in the journal.
+// Only the instances with an old-* copy are renamed back, so a run that failed part
+// way through renaming to old- can be rolled back too
+func (s *SDK) Rollback(names []string, opts *MigrateOptions) error {
+	journal, err := LoadMigrationState(opts.StateFile)
+	if err != nil {
+		return err
+	}
+
+	if len(names) == 0 {
+		for name := range journal.Instances {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	for _, name := range names {
+		m := &migration{s: s, opts: opts, name: name, journal: journal, state: journal.Instance(name)}
+		if err := m.rollback(); err != nil {
+			return fmt.Errorf("ERROR: Rollback(%s) failed with: %v", name, err)
+		}
+	}
+	return nil
+}
+
+func (m *migration) rollback() error {
+	// what to undo is decided by what's there, not by the journal: a run that failed
+	// half way through renaming to old- has to be rolled back too
+	aside, back := []renamePair{}, []renamePair{}
+	for _, name := range m.all() {
+		if !m.exists(m.oldName(name)) {
+			continue
+		}
+		// if we got as far as renaming back, the original name now belongs to the
+		// encrypted instance and it has to make room first
+		if m.exists(name) {
+			aside = append(aside, renamePair{from: name, to: m.newName(name)})
+		}
+		back = append(back, renamePair{from: m.oldName(name), to: name})
+	}
+	if len(back) == 0 && !m.state.Done(migrationSteps[0].name) {
+		m.s.log.Printf("... Rollback: [%24s] nothing to roll back, never renamed to %s", m.name, oldPrefix)
+		return nil
+	}
+
+	// an interrupted rename has to finish before it can be undone
+	for _, p := range back {
+		if err := m.s.waitForDBStatusWithin(p.from, m.opts.WaitTimeout, m.s.availableFunc("Rollback")); err != nil {
+			return err
+		}
+	}
+
+	if len(aside) > 0 {
+		m.s.log.Printf("... Rollback: [%24s] moving %d encrypted instance(s) aside", m.name, len(aside))
+		if err := m.s.renameAll(aside, m.opts.WaitTimeout); err != nil {
+			return err
+		}
+	}
+
+	if len(back) > 0 {
+		m.s.log.Printf("... Rollback: [%24s] renaming %d instance(s) back to their original names", m.name, len(back))
+		if err := m.s.renameAll(back, m.opts.WaitTimeout); err != nil {
+			return err
+		}
+	}
+
+	for _, p := range back {
+		m.s.log.Printf("... Rollback: [%24s] rebooting %q", m.name, p.to)
+		if err := m.s.Reboot(p.to, false); err != nil {
+			return err
+		}
+	}
+	for _, p := range back {
+		if err := m.s.waitForDBStatusWithin(p.to, m.opts.WaitTimeout, m.s.availableFunc("Rollback")); err != nil {
+			return err
+		}
+	}
+
+	if err := m.verifyRollback(); err != nil {
+		return err
+	}
+
+	return m.update(func(is *InstanceState) { is.RolledBack = true })
+}
+
+// verifyRollback - endpoints and replica topology have to match the pre-migration journal
+func (m *migration) verifyRollback() error {
+	for _, name := range m.all() {
+		i, err := m.s.Describe(name)
+		if err != nil {
+			return err
+		}
+		if want, got := m.state.Endpoints[name], endpointAddress(i); want != "" && want != got {
+			return fmt.Errorf("%q endpoint is %q, was %q before migration", name, got, want)
+		}
+	}
+
+	master, err := m.s.Describe(m.name)
+	if err != nil {
+		return err
+	}
+	got := []string{}
+	for _, replica := range master.RDSDBInstance.ReadReplicaDBInstanceIdentifiers {
+		got = append(got, *replica)
+	}
+	want := append([]string{}, m.state.Replicas...)
+	sort.Strings(got)
+	sort.Strings(want)
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		return fmt.Errorf("%q replicas are %v, were %v before migration", m.name, got, want)
+	}
+
+	m.s.log.Printf("... Rollback: [%24s] endpoints and %d replica(s) match pre-migration state", m.name, len(want))
+	return nil
+}
+
+func (m *migration) exists(name string) bool {
+	_, err := m.s.Describe(name)
+	if err != nil && !AWSError(err, rds.ErrCodeDBInstanceNotFoundFault) {
+		m.s.log.Printf("... Rollback: [%24s] can't describe %q: %v", m.name, name, err)
+	}
+	return err == nil
+}
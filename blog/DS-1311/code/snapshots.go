@@ -0,0 +1,199 @@
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+const (
+	runIDTag        = "encryption-migration-run"
+	snapRoleTag     = "encryption-migration-snapshot"
+	snapRoleSource  = "source"
+	snapRoleCopy    = "encrypted"
+	encryptedSuffix = "-encrypted"
+)
+
+// SnapshotManager - snapshot lifecycle of a migration run: create a manual snapshot,
+// copy it into a KMS key, tag both with the run ID and, once the migration is
+// confirmed, clean up the intermediate unencrypted snapshots
+type SnapshotManager struct {
+	s           *SDK
+	RunID       string
+	WaitTimeout time.Duration // per snapshot (or copy) to become available, 0 means defaultWaitTimeout
+}
+
+// NewSnapshotManager - snapshots are named and tagged after `runID`
+func (s *SDK) NewSnapshotManager(runID string) *SnapshotManager {
+	return &SnapshotManager{s: s, RunID: runID}
+}
+
+func (sm *SnapshotManager) tags(role string) []*rds.Tag {
+	return []*rds.Tag{
+		{Key: aws.String(runIDTag), Value: aws.String(sm.RunID)},
+		{Key: aws.String(snapRoleTag), Value: aws.String(role)},
+	}
+}
+
+// Create - take a manual snapshot of `instanceID` and wait for it,
+// returns the existing one if this run already took it
+func (sm *SnapshotManager) Create(instanceID string) (*rds.DBSnapshot, error) {
+	id := instanceID + "-" + sm.RunID
+	existing, err := sm.describe(id)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		sm.s.log.Printf("... SnapshotManager: [%24s] snapshot %q already exists", instanceID, id)
+		return sm.waitForSnapshot(id)
+	}
+
+	sm.s.log.Printf("... SnapshotManager: [%24s] creating snapshot %q", instanceID, id)
+	if _, err := sm.s.svc.CreateDBSnapshotWithContext(sm.s.ctx, &rds.CreateDBSnapshotInput{
+		DBInstanceIdentifier: aws.String(instanceID),
+		DBSnapshotIdentifier: aws.String(id),
+		Tags:                 sm.tags(snapRoleSource),
+	}); err != nil {
+		return nil, fmt.Errorf("ERROR: CreateDBSnapshotWithContext(%s) failed with: %v", instanceID, err)
+	}
+
+	return sm.waitForSnapshot(id)
+}
+
+// Encrypt - copy `snap` into `kmsKeyID` and wait for the copy
+func (sm *SnapshotManager) Encrypt(snap *rds.DBSnapshot, kmsKeyID string) (*rds.DBSnapshot, error) {
+	id := *snap.DBSnapshotIdentifier + encryptedSuffix
+	existing, err := sm.describe(id)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		sm.s.log.Printf("... SnapshotManager: [%24s] encrypted copy %q already exists", *snap.DBInstanceIdentifier, id)
+		return sm.waitForSnapshot(id)
+	}
+
+	sm.s.log.Printf("... SnapshotManager: [%24s] copying %q to %q with KMS key %q", *snap.DBInstanceIdentifier, *snap.DBSnapshotIdentifier, id, kmsKeyID)
+	if _, err := sm.s.svc.CopyDBSnapshotWithContext(sm.s.ctx, &rds.CopyDBSnapshotInput{
+		SourceDBSnapshotIdentifier: snap.DBSnapshotIdentifier,
+		TargetDBSnapshotIdentifier: aws.String(id),
+		KmsKeyId:                   aws.String(kmsKeyID),
+		Tags:                       sm.tags(snapRoleCopy),
+	}); err != nil {
+		return nil, fmt.Errorf("ERROR: CopyDBSnapshotWithContext(%s) failed with: %v", *snap.DBSnapshotIdentifier, err)
+	}
+
+	return sm.waitForSnapshot(id)
+}
+
+// CreateEncrypted - Create followed by Encrypt
+func (sm *SnapshotManager) CreateEncrypted(instanceID, kmsKeyID string) (*rds.DBSnapshot, error) {
+	snap, err := sm.Create(instanceID)
+	if err != nil {
+		return nil, err
+	}
+	return sm.Encrypt(snap, kmsKeyID)
+}
+
+// List - manual snapshots tagged with this run, `role` is either
+// snapRoleSource or snapRoleCopy, empty for both
+func (sm *SnapshotManager) List(role string) ([]*rds.DBSnapshot, error) {
+	snaps := []*rds.DBSnapshot{}
+	if err := sm.s.svc.DescribeDBSnapshotsPagesWithContext(sm.s.ctx, &rds.DescribeDBSnapshotsInput{
+		SnapshotType: aws.String("manual"),
+	}, func(page *rds.DescribeDBSnapshotsOutput, lastPage bool) bool {
+		for _, snap := range page.DBSnapshots {
+			if tagValue(snap.TagList, runIDTag) != sm.RunID {
+				continue
+			}
+			if role != "" && tagValue(snap.TagList, snapRoleTag) != role {
+				continue
+			}
+			snaps = append(snaps, snap)
+		}
+		return true
+	}); err != nil {
+		return nil, fmt.Errorf("ERROR: DescribeDBSnapshotsPagesWithContext failed with: %v", err)
+	}
+	return snaps, nil
+}
+
+// CleanupUnencrypted - delete this run's intermediate unencrypted snapshots, only
+// call this once the migration is confirmed, the encrypted copies are kept
+func (sm *SnapshotManager) CleanupUnencrypted(dryRun bool) error {
+	snaps, err := sm.List(snapRoleSource)
+	if err != nil {
+		return err
+	}
+
+	for _, snap := range snaps {
+		if aws.BoolValue(snap.Encrypted) {
+			continue
+		}
+		sm.s.log.Printf("... SnapshotManager: [%24s] deleting unencrypted snapshot %q", aws.StringValue(snap.DBInstanceIdentifier), *snap.DBSnapshotIdentifier)
+		if dryRun {
+			continue
+		}
+		if _, err := sm.s.svc.DeleteDBSnapshotWithContext(sm.s.ctx, &rds.DeleteDBSnapshotInput{
+			DBSnapshotIdentifier: snap.DBSnapshotIdentifier,
+		}); err != nil {
+			return fmt.Errorf("ERROR: DeleteDBSnapshotWithContext(%s) failed with: %v", *snap.DBSnapshotIdentifier, err)
+		}
+	}
+	return nil
+}
+
+// describe - nil if `id` doesn't exist
+func (sm *SnapshotManager) describe(id string) (*rds.DBSnapshot, error) {
+	out, err := sm.s.svc.DescribeDBSnapshotsWithContext(sm.s.ctx, &rds.DescribeDBSnapshotsInput{
+		DBSnapshotIdentifier: aws.String(id),
+	})
+	if err != nil {
+		if AWSError(err, rds.ErrCodeDBSnapshotNotFoundFault) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if len(out.DBSnapshots) == 0 {
+		return nil, nil
+	}
+	return out.DBSnapshots[0], nil
+}
+
+// waitForSnapshot - wait for `id` to become available, reporting progress along the way
+func (sm *SnapshotManager) waitForSnapshot(id string) (*rds.DBSnapshot, error) {
+	var snap *rds.DBSnapshot
+	err := poll(sm.s.ctx, sm.WaitTimeout, func() (bool, error) {
+		var err error
+		if snap, err = sm.describe(id); err != nil {
+			return false, err
+		}
+		if snap == nil {
+			return false, fmt.Errorf("snapshot %q disappeared", id)
+		}
+
+		status := aws.StringValue(snap.Status)
+		if status == Available {
+			sm.s.log.Printf("... SnapshotManager: [%24s] snapshot %q is available", aws.StringValue(snap.DBInstanceIdentifier), id)
+			return true, nil
+		}
+		sm.s.log.Printf("... SnapshotManager: [%24s] snapshot %q is %q: %d%%", aws.StringValue(snap.DBInstanceIdentifier), id, status, aws.Int64Value(snap.PercentProgress))
+		return false, nil
+	})
+	if err != nil {
+		status := ""
+		if snap != nil {
+			status = aws.StringValue(snap.Status)
+		}
+		return nil, timedOut(err, id, status, "")
+	}
+	return snap, nil
+}
+
+func tagValue(tags []*rds.Tag, key string) string {
+	for _, t := range tags {
+		if aws.StringValue(t.Key) == key {
+			return aws.StringValue(t.Value)
+		}
+	}
+	return ""
+}
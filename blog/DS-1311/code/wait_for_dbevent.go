@@ -0,0 +1,142 @@
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+)
+
+// RDS event messages (prefixes) we wait on, see:
+//
+//	https://docs.aws.amazon.com/AmazonRDS/latest/UserGuide/USER_Events.Messages.html
+const (
+	eventRestarted     = "DB instance restarted"
+	eventParamsApplied = "Updated to use DBParameterGroup"
+)
+
+// emptyPollsBeforeDescribe - consecutive DescribeEvents calls without a new event after
+// which waitForDBEvent falls back to a Describe, in case the event we want never shows up
+const emptyPollsBeforeDescribe = 3
+
+// EventRecord - a single RDS event, as it goes into the migration report
+type EventRecord struct {
+	Time    time.Time `json:"time"`
+	Source  string    `json:"source"`
+	Message string    `json:"message"`
+}
+
+func (e EventRecord) String() string {
+	return fmt.Sprintf("%s [%24s] %s", e.Time.Format(time.RFC3339), e.Source, e.Message)
+}
+
+// EventTimeline - every RDS event seen while waiting, safe for concurrent use
+type EventTimeline struct {
+	mu     sync.Mutex
+	seen   map[string]bool
+	events []EventRecord
+}
+
+// add - record `e` unless we have already seen it, returns true if it's new
+func (t *EventTimeline) add(e *rds.Event) bool {
+	return t.record(EventRecord{
+		Time:    aws.TimeValue(e.Date),
+		Source:  aws.StringValue(e.SourceIdentifier),
+		Message: aws.StringValue(e.Message),
+	})
+}
+
+// record - add, for events already turned into an EventRecord (ie: RebootResult.Events)
+func (t *EventTimeline) record(r EventRecord) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := r.String()
+	if t.seen == nil {
+		t.seen = make(map[string]bool)
+	}
+	if t.seen[key] {
+		return false
+	}
+	t.seen[key] = true
+	t.events = append(t.events, r)
+	return true
+}
+
+// Events - all events seen so far, oldest first
+func (t *EventTimeline) Events() []EventRecord {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	events := append([]EventRecord{}, t.events...)
+	sort.SliceStable(events, func(a, b int) bool { return events[a].Time.Before(events[b].Time) })
+	return events
+}
+
+// waitForDBEvent - alternative to waitForDBStatus which watches DescribeEvents for `id`
+// (since `since`) instead of polling Describe: only once one of the `want` events shows
+// up is `ready` confirmed with a single Describe, and only after emptyPollsBeforeDescribe
+// polls in a row without new events do we fall back to Describe. Every event is added to
+// `timeline`, including the ones that showed up while that last Describe was in flight.
+// Gives up after `timeout` (defaultWaitTimeout if 0) with a WaitTimeoutError.
+func (s *SDK) waitForDBEvent(id string, since time.Time, timeout time.Duration, want []string, ready dbReady, timeline *EventTimeline) error {
+	last := Instance{}
+	empty := 0
+	err := poll(s.ctx, timeout, func() (bool, error) {
+		fresh, wanted, err := s.collectDBEvents(id, since, want, timeline)
+		if err != nil {
+			return false, err
+		}
+
+		if fresh > 0 {
+			empty = 0
+		} else {
+			empty++
+		}
+		if !wanted && empty < emptyPollsBeforeDescribe {
+			return false, nil
+		}
+		empty = 0
+		i, err := s.Describe(id)
+		if err != nil {
+			return false, err
+		}
+		last = i
+		return ready(i), nil
+	})
+	if err != nil {
+		return timedOut(err, id, last.Status, last.ParGroupStatus)
+	}
+	_, _, err = s.collectDBEvents(id, since, want, timeline)
+	return err
+}
+
+// collectDBEvents - add the events of `id` since `since` to `timeline`, returns how many
+// of them are new and whether one of those is one of the `want` events
+func (s *SDK) collectDBEvents(id string, since time.Time, want []string, timeline *EventTimeline) (int, bool, error) {
+	out, err := s.svc.DescribeEventsWithContext(s.ctx, &rds.DescribeEventsInput{
+		SourceIdentifier: aws.String(id),
+		SourceType:       aws.String(rds.SourceTypeDbInstance),
+		StartTime:        aws.Time(since),
+	})
+	if err != nil {
+		return 0, false, fmt.Errorf("ERROR: DescribeEventsWithContext(%s) failed with: %v", id, err)
+	}
+
+	fresh, wanted := 0, false
+	for _, e := range out.Events {
+		if !timeline.add(e) {
+			continue
+		}
+		fresh++
+		s.log.Printf("... waitForDBEvent: [%24s] event: %s", id, aws.StringValue(e.Message))
+		for _, w := range want {
+			if strings.HasPrefix(aws.StringValue(e.Message), w) {
+				wanted = true
+			}
+		}
+	}
+	return fresh, wanted, nil
+}
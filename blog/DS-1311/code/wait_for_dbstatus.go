@@ -1,21 +1,118 @@
-import "time"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+const (
+	maxSleep           = 60 * 1000 // ms, cap on the backoff between Describe calls
+	defaultWaitTimeout = 6 * time.Hour
+)
 
 type dbReady func(Instance) bool
 
+// WaitTimeoutError - instance (or snapshot) did not become ready within the deadline,
+// carries the last observed status to tell stuck from just slow
+type WaitTimeoutError struct {
+	ID             string
+	Status         string
+	ParGroupStatus string
+	Waited         time.Duration
+}
+
+func (e *WaitTimeoutError) Error() string {
+	return fmt.Sprintf("timed out after %s waiting for %q, last status: %q parameter group status: %q",
+		e.Waited.Round(time.Second), e.ID, e.Status, e.ParGroupStatus)
+}
+
 func (s *SDK) waitForDBStatus(id string, ready dbReady) error {
-	sleep := defaultSleep
-	for {
-		time.Sleep(time.Millisecond * time.Duration(sleep))
+	return s.waitForDBStatusWithin(id, defaultWaitTimeout, ready)
+}
+
+// waitForDBStatusWithin - poll Describe until `ready`, `timeout` passes (WaitTimeoutError)
+// or s.ctx is cancelled
+func (s *SDK) waitForDBStatusWithin(id string, timeout time.Duration, ready dbReady) error {
+	last := Instance{}
+	err := poll(s.ctx, timeout, func() (bool, error) {
 		i, err := s.Describe(id)
 		if err != nil {
-			return err
+			return false, err
 		}
+		last = i
+		return ready(i), nil
+	})
+	return timedOut(err, id, last.Status, last.ParGroupStatus)
+}
+
+// poll - call `fn` until it's done or fails, `timeout` (defaultWaitTimeout if 0) passes or
+// `ctx` is cancelled, backing off exponentially up to maxSleep with some jitter so parallel
+// waits don't all hit the API at the same time. Times out with a WaitTimeoutError that only
+// knows how long it waited, see timedOut.
+func poll(ctx context.Context, timeout time.Duration, fn func() (bool, error)) error {
+	if timeout == 0 {
+		timeout = defaultWaitTimeout
+	}
+	wctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
 
-		if ready(i) {
-			break
+	start := time.Now()
+	sleep := defaultSleep
+	for {
+		select {
+		case <-wctx.Done():
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			return &WaitTimeoutError{Waited: time.Since(start)}
+		case <-time.After(jitter(sleep)):
+		}
+
+		done, err := fn()
+		if err != nil || done {
+			return err
 		}
 
 		sleep = sleep * drift
+		if sleep > maxSleep {
+			sleep = maxSleep
+		}
+	}
+}
+
+// timedOut - fill in what was being waited for, if `err` is poll's WaitTimeoutError
+func timedOut(err error, id, status, parGroupStatus string) error {
+	var te *WaitTimeoutError
+	if errors.As(err, &te) {
+		te.ID, te.Status, te.ParGroupStatus = id, status, parGroupStatus
+	}
+	return err
+}
+
+// pause - sleep for `ms` milliseconds unless s.ctx is cancelled first
+func (s *SDK) pause(ms int) error {
+	select {
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	case <-time.After(time.Millisecond * time.Duration(ms)):
+		return nil
+	}
+}
+
+// jitter - `ms` milliseconds +/- 20%
+func jitter(ms int) time.Duration {
+	return time.Duration(float64(ms) * (0.8 + 0.4*rand.Float64()) * float64(time.Millisecond))
+}
+
+// availableFunc - dbReady func waiting for the instance to become Available,
+// `caller` is used as the log prefix
+func (s *SDK) availableFunc(caller string) dbReady {
+	return func(i Instance) bool {
+		ok := i.Status == Available
+		if !ok {
+			s.log.Printf("... %s: [%24s] waiting for DB status to change from %q to %q", caller, i.Name, i.Status, Available)
+		}
+		return ok
 	}
-	return nil
 }